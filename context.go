@@ -38,3 +38,12 @@ func FromContext(ctx context.Context) TextFinder {
 	// use default
 	return Default()
 }
+
+// LanguageFromContext returns the language of the TextFinder bound into the context.
+// False is returned if no finder is bound or the bound finder does not implement LanguageFinder.
+func LanguageFromContext(ctx context.Context) (Tag, bool) {
+	if lf, ok := ctx.Value(lpaxContextKey).(LanguageFinder); ok {
+		return lf.Language(), true
+	}
+	return Tag{}, false
+}
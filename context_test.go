@@ -19,6 +19,8 @@ package lpax
 import (
 	"context"
 	"testing"
+
+	"golang.org/x/text/language"
 )
 
 func TestContext(t *testing.T) {
@@ -65,3 +67,20 @@ func TestContextNoSet(t *testing.T) {
 		t.Error("ctx message", msg)
 	}
 }
+
+func TestLanguageFromContext(t *testing.T) {
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.French))
+
+	tag, ok := LanguageFromContext(ctx)
+	if !ok || tag != language.French {
+		t.Error("language", tag, ok)
+	}
+
+	if _, ok = LanguageFromContext(WithContext(context.Background(), TextMap{})); ok {
+		t.Error("TextMap has language")
+	}
+
+	if _, ok = LanguageFromContext(context.Background()); ok {
+		t.Error("unbound has language")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"encoding/json"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+type (
+	// CoverageReport compares the text registered in each language of each pack to the DefaultLanguage.
+	// The report is encoded as JSON with keys written as their TextCode, followed by :plural for plural keys.
+	CoverageReport struct {
+		// Default is the language compared against.
+		Default Tag `json:"default"`
+
+		// Packs are the registered packs ordered by name.
+		Packs []PackCoverage `json:"packs"`
+	}
+
+	// PackCoverage is the coverage of a pack.
+	PackCoverage struct {
+		// ID is the pack's id.
+		ID PackID `json:"-"`
+
+		// Name is the type qualified value of the id, e.g. messages.PackID(1).
+		Name string `json:"id"`
+
+		// Default is the registered language of the pack matching the DefaultLanguage.
+		Default Tag `json:"default"`
+
+		// Keys is the number of keys in the default language.
+		Keys int `json:"keys"`
+
+		// Languages are the other registered languages of the pack ordered by tag.
+		Languages []LanguageCoverage `json:"languages"`
+	}
+
+	// LanguageCoverage compares the text of a pack in a language with the default language.
+	// Plural category variant keys, added by ByCategory and ByOrdinalCategory, depend on the language's
+	// plural rules and are not compared.
+	LanguageCoverage struct {
+		// Language is the language compared.
+		Language Tag
+
+		// Translated is the number of default language keys the language has.
+		Translated int
+
+		// Missing are the default language keys the language does not have.
+		Missing []TextID
+
+		// Extra are the keys the language has that the default language does not.
+		Extra []TextID
+
+		// Mismatched are the keys whose fmt verbs are not compatible with the default language's, see ValidateFormat.
+		Mismatched []TextID
+	}
+)
+
+// Coverage compares the text registered in each language of each pack in the registry with the
+// text registered in the DefaultLanguage.  The report has no packs if the registry does not implement
+// IntrospectRegistry.
+func Coverage(r TextRegistry) *CoverageReport {
+	defaultTag := language.MustParse(DefaultLanguage)
+	report := &CoverageReport{Default: defaultTag, Packs: []PackCoverage{}}
+
+	ir, ok := r.(IntrospectRegistry)
+	if !ok {
+		return report
+	}
+
+	for _, info := range ir.Packs() {
+		pc := PackCoverage{
+			ID:        info.ID,
+			Name:      info.Name,
+			Default:   matchLanguage(info.Languages, []Tag{defaultTag}),
+			Languages: []LanguageCoverage{},
+		}
+
+		reference := ir.PackText(info.ID, pc.Default)
+		pc.Keys = len(compared(reference))
+
+		for _, tag := range info.Languages {
+			if tag != pc.Default {
+				pc.Languages = append(pc.Languages, compareCoverage(tag, reference, ir.PackText(info.ID, tag)))
+			}
+		}
+
+		sort.Slice(pc.Languages, func(i, j int) bool {
+			return pc.Languages[i].Language.String() < pc.Languages[j].Language.String()
+		})
+
+		report.Packs = append(report.Packs, pc)
+	}
+
+	return report
+}
+
+// compareCoverage compares the text of a language with the reference text.
+func compareCoverage(langTag Tag, reference, tm TextMap) LanguageCoverage {
+	lc := LanguageCoverage{Language: langTag}
+
+	for k, f := range compared(reference) {
+		t, ok := tm[k]
+
+		switch {
+		case !ok:
+			lc.Missing = append(lc.Missing, k)
+		case len(ValidateFormat(f, t)) > 0:
+			lc.Translated++
+			lc.Mismatched = append(lc.Mismatched, k)
+		default:
+			lc.Translated++
+		}
+	}
+
+	for k := range compared(tm) {
+		if _, ok := reference[k]; !ok {
+			lc.Extra = append(lc.Extra, k)
+		}
+	}
+
+	sortKeys(lc.Missing)
+	sortKeys(lc.Extra)
+	sortKeys(lc.Mismatched)
+
+	return lc
+}
+
+// compared returns the text map without its plural category variants.
+func compared(tm TextMap) TextMap {
+	keys := make(TextMap, len(tm))
+	for k, v := range tm {
+		if k == k.Single() || k == k.Plural() {
+			keys[k] = v
+		}
+	}
+	return keys
+}
+
+// KeyName returns the language independent name of a TextMap key, the TextCode of the id followed
+// by :plural for the plural version of a message.  Plural category variants are named by their String.
+func KeyName(id TextID) string {
+	switch {
+	case id != id.Single() && id != id.Plural():
+		return id.String()
+	case id == id.Plural() && id != id.Single():
+		return TextCode(id) + ":plural"
+	}
+	return TextCode(id)
+}
+
+// sortKeys orders keys by name.
+func sortKeys(keys []TextID) {
+	sort.Slice(keys, func(i, j int) bool {
+		return KeyName(keys[i]) < KeyName(keys[j])
+	})
+}
+
+// keyNames returns the names of keys, an empty list rather than nil.
+func keyNames(keys []TextID) []string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = KeyName(k)
+	}
+	return names
+}
+
+// MarshalJSON encodes the coverage with its keys written as their KeyName.
+func (lc LanguageCoverage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Language   Tag      `json:"language"`
+		Translated int      `json:"translated"`
+		Missing    []string `json:"missing"`
+		Extra      []string `json:"extra"`
+		Mismatched []string `json:"mismatched"`
+	}{
+		Language:   lc.Language,
+		Translated: lc.Translated,
+		Missing:    keyNames(lc.Missing),
+		Extra:      keyNames(lc.Extra),
+		Mismatched: keyNames(lc.Mismatched),
+	})
+}
@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func newCoverageRegistry() TextRegistry {
+	r := NewRegistry()
+
+	texts := map[Tag]TextMap{
+		language.English: {Hello: "Hello World", Args: "Single %v", -Args: "Plural %v", ByCategory(Args, PluralFew): "Few %v"},
+		language.Spanish: {Hello: "Hola", Args: "Uno %d", None: "Nada"},
+		language.French:  {Hello: "Bonjour"},
+	}
+
+	onRegister := func(packID PackID, langTag Tag) TextMap {
+		return texts[langTag]
+	}
+
+	r.Register(ExamplePackID, onRegister, Package, language.English, language.Spanish)
+	r.Register(ExamplePackID, onRegister, AdditionalPacks, language.French)
+	r.Register(TestPackID(2), onRegister, Override, language.French)
+
+	return r
+}
+
+func TestPacks(t *testing.T) {
+	packs := newCoverageRegistry().(IntrospectRegistry).Packs()
+
+	if len(packs) != 2 || packs[0].Name != "lpax.TestPackID(1)" || packs[1].ID != TestPackID(2) {
+		t.Fatal("packs", packs)
+	}
+
+	if !reflect.DeepEqual(packs[0].Languages, []Tag{language.English, language.Spanish, language.French}) {
+		t.Error("languages", packs[0].Languages)
+	}
+
+	if len(packs[0].Registrations) != 2 || packs[0].Registrations[0].Priority != Package ||
+		packs[0].Registrations[1].Priority != AdditionalPacks {
+		t.Error("registrations", packs[0].Registrations)
+	}
+}
+
+func TestPackText(t *testing.T) {
+	r := newCoverageRegistry().(IntrospectRegistry)
+
+	if tm := r.PackText(ExamplePackID, language.Spanish); tm.Text(Hello) != "Hola" {
+		t.Error("spanish", tm)
+	}
+
+	if tm := r.PackText(ExamplePackID, language.German); tm != nil {
+		t.Error("german", tm)
+	}
+
+	if tm := r.PackText(TestPackID(3), language.English); tm != nil {
+		t.Error("unregistered", tm)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	report := Coverage(newCoverageRegistry())
+
+	if len(report.Packs) != 2 {
+		t.Fatal("packs", report.Packs)
+	}
+
+	pc := report.Packs[0]
+	if pc.Default != language.English || pc.Keys != 3 || len(pc.Languages) != 2 {
+		t.Fatal("pack", pc)
+	}
+
+	es := pc.Languages[0]
+	if es.Language != language.Spanish || es.Translated != 2 ||
+		!reflect.DeepEqual(es.Missing, []TextID{-Args}) ||
+		!reflect.DeepEqual(es.Extra, []TextID{None}) ||
+		!reflect.DeepEqual(es.Mismatched, []TextID{Args}) {
+		t.Error("spanish", es)
+	}
+
+	fr := pc.Languages[1]
+	if fr.Language != language.French || fr.Translated != 1 || len(fr.Missing) != 2 || fr.Extra != nil {
+		t.Error("french", fr)
+	}
+
+	// a pack without the default language has nothing to compare against
+	if other := report.Packs[1]; other.Keys != 0 || len(other.Languages) != 1 || len(other.Languages[0].Extra) != 1 {
+		t.Error("other", other)
+	}
+
+	if report := Coverage(wrappedRegistry{newCoverageRegistry()}); len(report.Packs) != 0 {
+		t.Error("not introspectable", report.Packs)
+	}
+}
+
+func TestCoverageJSON(t *testing.T) {
+	b, err := json.Marshal(Coverage(newCoverageRegistry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(b)
+	for _, want := range []string{
+		`"default":"en"`,
+		`"id":"lpax.TestPackID(1)"`,
+		`"language":"es","translated":2,"missing":["3:plural"],"extra":["1"],"mismatched":["3"]`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Error("missing", want, s)
+		}
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	for id, want := range map[TextID]string{
+		Hello:                               "2",
+		-Hello:                              "2:plural",
+		ByCategory(Hello, PluralFew):        "2[few]",
+		ByOrdinalCategory(Hello, PluralTwo): "2[ordinal-two]",
+	} {
+		if s := KeyName(id); s != want {
+			t.Error(id, s)
+		}
+	}
+}
+
+func TestParseVerbs(t *testing.T) {
+	for format, want := range map[string][]formatVerb{
+		"100%%":          nil,
+		"%s and %5.2f":   {{0, 's'}, {1, 'f'}},
+		"%*d":            {{0, '*'}, {1, 'd'}},
+		"%[2]v %[1]v %v": {{1, 'v'}, {0, 'v'}, {1, 'v'}},
+		"%-[1]*x":        {{0, '*'}, {1, 'x'}},
+		"%é":             {{0, 'é'}},
+		"%":              nil,
+	} {
+		if got, _ := parseVerbs(format); !reflect.DeepEqual(got, want) {
+			t.Error(format, got)
+		}
+	}
+
+	if _, reordered := parseVerbs("%[1]s"); !reordered {
+		t.Error("reordered")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nehemming/lpax/internal/printf"
+)
+
+// Error is a localized error.  The error retains the TextID and args it was created with
+// allowing it to be rendered again in any language.
+// Error is returned by Errorf, CtxErrorf, ErrorNamed and CtxErrorNamed.
+type Error struct {
+	// ID is the text id of the error message.
+	ID TextID
+
+	// Args are the args passed to the error message format.
+	Args []interface{}
+
+	// Named are the named values of an error created by ErrorNamed or CtxErrorNamed, replacing the
+	// {name} placeholders of the error's MessageFormat pattern.  Named is nil for fmt formatted errors.
+	Named MessageArgs
+
+	// finder is the text finder the error was created with.
+	finder TextFinder
+
+	// mode is the MissingMode of the finder's policy if the text was missing when the error was created.
+	mode MissingMode
+
+	// missing is set if the text was missing when the error was created and the finder's policy is MissingError.
+	missing *MissingTextError
+}
+
+// NewError creates a new Error rendered using the default text finder.
+// Text missing from the default text finder is reported to its MissingPolicy when the error is created.
+func NewError(id TextID, args ...interface{}) *Error {
+	return newError(nil, id, args)
+}
+
+// newError creates an Error rendered using tf, or the default text finder if tf is nil, reporting
+// missing text once so that a MissingPanic policy panics when the error is created rather than when
+// it is printed and rendering the error does not count the miss again.
+func newError(tf TextFinder, id TextID, args []interface{}) *Error {
+	e := &Error{ID: id, Args: args, finder: tf}
+
+	if tf == nil {
+		tf = Default()
+	}
+
+	if _, found := tf.Find(id); !found {
+		e.mode = reportMissing(tf, id)
+		if e.mode == MissingError {
+			e.missing = &MissingTextError{Language: finderLanguage(tf), ID: id}
+		}
+	}
+
+	return e
+}
+
+// Error returns the error message rendered by the text finder the error was created with.
+// Missing text was reported when the error was created and is not reported again.
+func (e *Error) Error() string {
+	tf := e.finder
+	if tf == nil {
+		tf = Default()
+	}
+
+	f, ok := tf.Find(e.ID)
+	if !ok {
+		return e.formatMissing(e.mode)
+	}
+
+	return e.format(tf, f)
+}
+
+// Render returns the error message rendered using the passed text finder.
+// If the string is not found it is reported to the finder's MissingPolicy and the string version
+// of the id is printed along with a space separated %v version of each arg.
+func (e *Error) Render(tf TextFinder) string {
+	f, ok := tf.Find(e.ID)
+	if !ok {
+		return e.formatMissing(reportMissing(tf, e.ID))
+	}
+
+	return e.format(tf, f)
+}
+
+// format renders the error's text f found by tf.
+func (e *Error) format(tf TextFinder, f string) string {
+	if e.Named != nil {
+		return formatNamed(tf, e.ID, f, e.Named)
+	}
+
+	// Errorf is used to support %w verbs in the format
+	return fmt.Errorf(f, e.Args...).Error()
+}
+
+// formatMissing renders the error printed by the mode when its text is missing.
+func (e *Error) formatMissing(mode MissingMode) string {
+	if e.Named != nil {
+		return formatMissingNamed(mode, e.ID, e.Named)
+	}
+
+	f, args := formatMissing(mode, e.ID, e.Args)
+	return fmt.Errorf(f, args...).Error()
+}
+
+// CtxRender returns the error message rendered using the text finder linked to the passed context.
+// If the context has no finder the default finder is used.
+func (e *Error) CtxRender(ctx context.Context) string {
+	return e.Render(FromContext(ctx))
+}
+
+// Code returns the language independent code of the error's TextID, see TextCode.
+func (e *Error) Code() string {
+	return TextCode(e.ID)
+}
+
+// Unwrap returns the error arg consumed by the first %w verb of the error's text, or nil if there is none.
+// Args printed by other verbs, the args of missing text and the named values of a MessageFormat pattern
+// are not unwrapped.
+func (e *Error) Unwrap() error {
+	if e.Named != nil {
+		return nil
+	}
+
+	tf := e.finder
+	if tf == nil {
+		tf = Default()
+	}
+
+	f, ok := tf.Find(e.ID)
+	if !ok {
+		return nil
+	}
+
+	for _, v := range printf.Parse(f) {
+		if v.Verb != 'w' || v.BadIndex || v.Arg >= len(e.Args) {
+			continue
+		}
+		if err, ok := e.Args[v.Arg].(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// Is returns true if target is an *Error with the same TextID, ignoring if either is the plural version.
+// This allows errors.Is(err, lpax.NewError(id)) to test an error chain for an id.
+// ErrMissingText is matched if the text was missing when the error was created and the finder's
+// MissingPolicy is MissingError.
+func (e *Error) Is(target error) bool {
+	if target == ErrMissingText {
+		return e.missing != nil
+	}
+
+	t, ok := target.(*Error)
+	return ok && t.ID != nil && e.ID != nil && t.ID.Single() == e.ID.Single()
+}
+
+// As sets a **MissingTextError target if the text was missing when the error was created and the
+// finder's MissingPolicy is MissingError.
+func (e *Error) As(target interface{}) bool {
+	t, ok := target.(**MissingTextError)
+	if ok && e.missing != nil {
+		*t = e.missing
+		return true
+	}
+	return false
+}
+
+// ErrorID returns the TextID of the first Error in err's chain.
+func ErrorID(err error) (TextID, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ID, true
+	}
+	return nil, false
+}
+
+// ErrorCode returns the code of the first Error in err's chain.
+func ErrorCode(err error) (string, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code(), true
+	}
+	return "", false
+}
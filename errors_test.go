@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+var frenchErrors = TextMap{
+	Args: "Unique %v",
+}
+
+func TestErrorRender(t *testing.T) {
+	err := Errorf(Args, 1)
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal("not an Error")
+	}
+
+	if s := e.Render(frenchErrors); s != "Unique 1" {
+		t.Error("Render", s)
+	}
+
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.French, frenchErrors))
+	if s := e.CtxRender(ctx); s != "Unique 1" {
+		t.Error("CtxRender", s)
+	}
+
+	if s := e.Render(TextMap{}); s != "3:1" {
+		t.Error("Render missing", s)
+	}
+}
+
+func TestCtxErrorfRendersWithContextFinder(t *testing.T) {
+	ctx := WithContext(context.Background(), frenchErrors)
+
+	err := CtxErrorf(ctx, Args, 2)
+	if err.Error() != "Unique 2" {
+		t.Error("Error", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Render(Default()) != "Single 2" {
+		t.Error("Render default", e)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", Errorf(Args, 1))
+
+	if !errors.Is(err, NewError(Args)) {
+		t.Error("Is Args")
+	}
+
+	if errors.Is(err, NewError(Hello)) {
+		t.Error("Is Hello")
+	}
+
+	if !errors.Is(Errorf(Args.Plural(), 2), NewError(Args)) || !errors.Is(err, NewError(Args.Plural())) {
+		t.Error("Is plural")
+	}
+
+	if errors.Is(err, io.EOF) {
+		t.Error("Is EOF")
+	}
+
+	id, ok := ErrorID(err)
+	if !ok || id != Args {
+		t.Error("ErrorID", id, ok)
+	}
+
+	if _, ok = ErrorID(io.EOF); ok {
+		t.Error("ErrorID EOF")
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	err := newError(TextMap{Args: "failed %d: %w"}, Args, []interface{}{2, io.EOF})
+
+	if !errors.Is(err, io.EOF) {
+		t.Error("Is EOF")
+	}
+
+	if errors.Unwrap(newError(TextMap{Args: "failed: %v"}, Args, []interface{}{io.EOF})) != nil {
+		t.Error("Unwrap verb v")
+	}
+
+	if errors.Unwrap(newError(TextMap{}, Args, []interface{}{io.EOF})) != nil {
+		t.Error("Unwrap missing")
+	}
+
+	if errors.Unwrap(newError(TextMap{Args: "failed: %[2]w"}, Args, []interface{}{io.EOF, io.ErrUnexpectedEOF})) != io.ErrUnexpectedEOF {
+		t.Error("Unwrap indexed")
+	}
+
+	if errors.Unwrap(NewError(Args, 1)) != nil {
+		t.Error("Unwrap no cause")
+	}
+}
+
+func TestErrorIsNilID(t *testing.T) {
+	if errors.Is(NewError(Args), &Error{}) || errors.Is(&Error{}, NewError(Args)) {
+		t.Error("Is nil id")
+	}
+}
+
+func TestErrorWrapVerb(t *testing.T) {
+	err := NewError(Args, io.EOF)
+
+	if s := err.Render(TextMap{Args: "failed: %w"}); s != "failed: EOF" {
+		t.Error("Render", s)
+	}
+}
+
+type codedTextID int
+
+func (id codedTextID) Single() TextID {
+	return codedTextID(IntTypeSingle(int(id)))
+}
+
+func (id codedTextID) Plural() TextID {
+	return codedTextID(IntTypePlural(int(id)))
+}
+
+func (id codedTextID) String() string {
+	return fmt.Sprintf("%d", int(id))
+}
+
+func (id codedTextID) Code() string {
+	return ReflectCoderString(id.Single())
+}
+
+func TestErrorCode(t *testing.T) {
+	if code := NewError(Args.Plural()).Code(); code != "3" {
+		t.Error("String code", code)
+	}
+
+	if code := NewError(codedTextID(-42)).Code(); code != "lpax-00042" {
+		t.Error("Coder code", code)
+	}
+
+	code, ok := ErrorCode(fmt.Errorf("wrapped: %w", CtxErrorf(context.Background(), codedTextID(7))))
+	if !ok || code != "lpax-00007" {
+		t.Error("ErrorCode", code, ok)
+	}
+
+	if _, ok = ErrorCode(io.EOF); ok {
+		t.Error("ErrorCode EOF")
+	}
+}
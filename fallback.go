@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/text/language"
+)
+
+// FallbackChain returns the languages, in order, whose text is used for keys missing from the text
+// registered for a language.  langTag is the registered language best matching the languages passed
+// to New.  Languages in the chain that a pack has not registered are skipped.
+type FallbackChain = func(langTag Tag) []Tag
+
+// FallbackRegistry is implemented by registries whose finders search a FallbackChain for missing text, such
+// as those created by NewRegistry.  The language supplying each text of the registry's shared provider is
+// reported by TextSource.
+type FallbackRegistry interface {
+	// SetFallback sets the FallbackChain searched, key by key, for text missing from the best matching
+	// language of each pack.  The default is CLDRFallback, nil disables fallback.
+	SetFallback(chain FallbackChain)
+}
+
+// CLDRFallback is the default FallbackChain, the CLDR parent locales of the language followed by the
+// DefaultLanguage, e.g. pt-AO falls back to pt-PT, pt and then en.
+func CLDRFallback(langTag Tag) []Tag {
+	var chain []Tag
+	for tag := langTag.Parent(); !tag.IsRoot(); tag = tag.Parent() {
+		chain = append(chain, tag)
+	}
+
+	return append(chain, language.MustParse(DefaultLanguage))
+}
+
+// FallbackChains returns a FallbackChain using the chain set for a language in chains, or CLDRFallback
+// for languages without one, e.g.
+//
+//	r.(lpax.FallbackRegistry).SetFallback(lpax.FallbackChains(map[lpax.Tag][]lpax.Tag{
+//	    language.BrazilianPortuguese: {language.EuropeanPortuguese, language.Portuguese, language.English},
+//	}))
+func FallbackChains(chains map[Tag][]Tag) FallbackChain {
+	cp := make(map[Tag][]Tag, len(chains))
+	for tag, chain := range chains {
+		cp[tag] = append([]Tag(nil), chain...)
+	}
+
+	return func(langTag Tag) []Tag {
+		if chain, ok := cp[langTag]; ok {
+			return chain
+		}
+		return CLDRFallback(langTag)
+	}
+}
+
+// SetFallback sets the FallbackChain used by the finders created by the registry, nil disables
+// fallback so only the text registered for the best matching language of each pack is used.
+func (r *packRegistry) SetFallback(chain FallbackChain) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallback = chain
+	atomic.AddUint64(&r.regSequence, 1)
+}
+
+// fallbackChain returns the distinct languages searched for text, the matched language first.
+func (r *packRegistry) fallbackChain(matchTag Tag) []Tag {
+	chain := []Tag{matchTag}
+	if r.fallback == nil {
+		return chain
+	}
+
+	seen := map[Tag]bool{matchTag: true}
+	for _, tag := range r.fallback(matchTag) {
+		if !seen[tag] {
+			seen[tag] = true
+			chain = append(chain, tag)
+		}
+	}
+
+	return chain
+}
+
+// TextSource returns the language whose registered text the finder uses for textID, false is
+// returned if the text is missing or the finder does not implement SourceFinder.
+func TextSource(tf TextFinder, textID TextID) (Tag, bool) {
+	if sf, ok := tf.(SourceFinder); ok {
+		return sf.Source(textID)
+	}
+	return Tag{}, false
+}
+
+// Source returns the registered language that supplied the text of textID.  Text passed to New and
+// synthesized pseudo-locale text is reported as the language of the finder.
+func (tm *languageTextMap) Source(textID TextID) (Tag, bool) {
+	if _, ok := tm.TextMap[textID]; !ok {
+		return Tag{}, false
+	}
+
+	if tag, ok := tm.sources[textID]; ok {
+		return tag, true
+	}
+	return tm.langTag, true
+}
+
+// Source returns the registered language that supplied the text of textID to the shared provider.
+func (r *packRegistry) Source(textID TextID) (Tag, bool) {
+	return r.initTextProvider().Source(textID)
+}
+
+// Source returns the registered language that supplied the text of textID to the current finder.
+func (lf *liveFinder) Source(textID TextID) (Tag, bool) {
+	return TextSource(lf.current(), textID)
+}
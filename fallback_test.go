@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func newFallbackRegistry() TextRegistry {
+	r := NewRegistry()
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		switch langTag {
+		case language.English:
+			return TextMap{Hello: "Hello World", Args: "Single %v", None: "Nothing"}
+		case language.Portuguese:
+			return TextMap{Hello: "Olá Mundo", Args: "Um %v"}
+		case language.EuropeanPortuguese:
+			return TextMap{Args: "Um só %v"}
+		case language.BrazilianPortuguese:
+			return TextMap{Hello: "Oi Mundo"}
+		}
+		return nil
+	}, Package, language.English, language.Portuguese, language.EuropeanPortuguese, language.BrazilianPortuguese)
+
+	return r
+}
+
+func TestCLDRFallback(t *testing.T) {
+	chain := CLDRFallback(language.MustParse("pt-AO"))
+	if !reflect.DeepEqual(chain, []Tag{language.EuropeanPortuguese, language.Portuguese, language.English}) {
+		t.Error("pt-AO", chain)
+	}
+
+	if chain := CLDRFallback(language.English); !reflect.DeepEqual(chain, []Tag{language.English}) {
+		t.Error("en", chain)
+	}
+}
+
+func TestFallbackPerKey(t *testing.T) {
+	r := newFallbackRegistry()
+	tf := NewFinder(r, language.BrazilianPortuguese)
+
+	// pt-BR falls back to pt and then en
+	for id, want := range map[TextID]string{Hello: "Oi Mundo", Args: "Um %v", None: "Nothing"} {
+		if s := tf.Text(id); s != want {
+			t.Error(id, s)
+		}
+	}
+
+	for id, want := range map[TextID]Tag{Hello: language.BrazilianPortuguese, Args: language.Portuguese, None: language.English} {
+		if tag, ok := TextSource(tf, id); !ok || tag != want {
+			t.Error("source", id, tag, ok)
+		}
+	}
+
+	if tag, ok := TextSource(tf, Hello.Plural()); ok {
+		t.Error("missing source", tag)
+	}
+
+	if lf, ok := tf.(LanguageFinder); !ok || lf.Language() != language.BrazilianPortuguese {
+		t.Error("language")
+	}
+}
+
+func TestFallbackChains(t *testing.T) {
+	r := newFallbackRegistry()
+	r.(FallbackRegistry).SetFallback(FallbackChains(map[Tag][]Tag{
+		language.BrazilianPortuguese: {language.EuropeanPortuguese, language.Portuguese, language.English},
+	}))
+
+	tf := NewFinder(r, language.BrazilianPortuguese)
+	if s := tf.Text(Args); s != "Um só %v" {
+		t.Error("args", s)
+	}
+
+	if tag, _ := TextSource(tf, Args); tag != language.EuropeanPortuguese {
+		t.Error("source", tag)
+	}
+
+	// languages without a chain use the CLDR parents
+	if s := NewFinder(r, language.EuropeanPortuguese).Text(Hello); s != "Olá Mundo" {
+		t.Error("pt-PT", s)
+	}
+}
+
+func TestFallbackDisabled(t *testing.T) {
+	r := newFallbackRegistry()
+	tf := NewFinder(r, language.BrazilianPortuguese)
+
+	r.(FallbackRegistry).SetFallback(nil)
+
+	// the cached finder is replaced
+	if s := NewFinder(r, language.BrazilianPortuguese).Text(Args); s != "" {
+		t.Error("args", s)
+	}
+
+	if s := tf.Text(Args); s != "Um %v" {
+		t.Error("snapshot", s)
+	}
+}
+
+func TestFallbackSourceOptions(t *testing.T) {
+	r := newFallbackRegistry()
+	tf := NewFinder(r, language.BrazilianPortuguese, TextMap{None: "Nada"})
+
+	if tag, ok := TextSource(tf, None); !ok || tag != language.BrazilianPortuguese {
+		t.Error("option", tag, ok)
+	}
+
+	if tag, ok := TextSource(NewLiveFinder(r, language.BrazilianPortuguese), Args); !ok || tag != language.Portuguese {
+		t.Error("live", tag, ok)
+	}
+
+	if _, ok := TextSource(TextMap{Hello: "Hello"}, Hello); ok {
+		t.Error("text map")
+	}
+
+	if _, ok := r.(SourceFinder); !ok {
+		t.Error("registry is not a SourceFinder")
+	}
+}
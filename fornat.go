@@ -31,9 +31,7 @@ func Sprintf(id TextID, args ...interface{}) string {
 	f, ok := tm.Find(id)
 
 	if !ok {
-		f = strings.TrimRight("%s:"+strings.Repeat("%v ", len(args)), " ")
-
-		args = append([]interface{}{id}, args...)
+		f, args = missingFormat(tm, id, args)
 	}
 
 	return fmt.Sprintf(f, args...)
@@ -49,9 +47,7 @@ func CtxSprintf(ctx context.Context, id TextID, args ...interface{}) string {
 	f, ok := tm.Find(id)
 
 	if !ok {
-		f = strings.TrimRight("%s:"+strings.Repeat("%v ", len(args)), " ")
-
-		args = append([]interface{}{id}, args...)
+		f, args = missingFormat(tm, id, args)
 	}
 
 	return fmt.Sprintf(f, args...)
@@ -60,34 +56,109 @@ func CtxSprintf(ctx context.Context, id TextID, args ...interface{}) string {
 // Errorf is identical to fmt.Errorf except the format string is taken from the default text finder.
 // If the string is not found the string version of the id is printed along with a
 // space separated %v version of each arg.
+// The returned error is an *Error which may be rendered again in other languages.
 func Errorf(id TextID, args ...interface{}) error {
-	tm := Default()
+	return NewError(id, args...)
+}
 
-	f, ok := tm.Find(id)
+// CtxErrorf is identical to fmt.Errorf except the format string is taken from the text finder
+// linked to the passed context. If the context has no finder the default finder is used.
+// If the string is not found the string version of the id is printed along with a
+// space separated %v version of each arg.
+// The returned error is an *Error which may be rendered again in other languages.
+func CtxErrorf(ctx context.Context, id TextID, args ...interface{}) error {
+	return newError(FromContext(ctx), id, args)
+}
+
+// SprintfCount is identical to Sprintf except the plural variant of the format string matching count
+// is selected using the CLDR plural rules of the default text finder's language.
+// count is not passed to the format, include it in args if it is to be printed.
+func SprintfCount(id TextID, count int, args ...interface{}) string {
+	tf := Default()
+
+	f, ok := FindCount(tf, id, count)
 
 	if !ok {
-		f = strings.TrimRight("%s:"+strings.Repeat("%v ", len(args)), " ")
+		f, args = missingFormat(tf, id, args)
+	}
+
+	return fmt.Sprintf(f, args...)
+}
+
+// CtxSprintfCount is identical to CtxSprintf except the plural variant of the format string matching count
+// is selected using the CLDR plural rules of the language of the text finder linked to the passed context.
+// count is not passed to the format, include it in args if it is to be printed.
+func CtxSprintfCount(ctx context.Context, id TextID, count int, args ...interface{}) string {
+	tf := FromContext(ctx)
 
-		args = append([]interface{}{id}, args...)
+	f, ok := FindCount(tf, id, count)
+
+	if !ok {
+		f, args = missingFormat(tf, id, args)
 	}
 
-	return fmt.Errorf(f, args...)
+	return fmt.Sprintf(f, args...)
 }
 
-// CtxErrorf is identical to fmt.Errorf except the format string is taken from the text finder
-// linked to the passed context. If the context has no finder the default finder is used.
-// If the string is not found the string version of the id is printed along with a
-// space separated %v version of each arg.
-func CtxErrorf(ctx context.Context, id TextID, args ...interface{}) error {
-	tm := FromContext(ctx)
+// SprintfOrdinal is identical to Sprintf except the ordinal variant of the format string for position n
+// is selected using the CLDR ordinal rules of the default text finder's language.
+// n is not passed to the format, include it in args if it is to be printed.
+func SprintfOrdinal(id TextID, n int, args ...interface{}) string {
+	tf := Default()
 
-	f, ok := tm.Find(id)
+	f, ok := FindOrdinal(tf, id, n)
 
 	if !ok {
-		f = strings.TrimRight("%s:"+strings.Repeat("%v ", len(args)), " ")
+		f, args = missingFormat(tf, id, args)
+	}
+
+	return fmt.Sprintf(f, args...)
+}
+
+// CtxSprintfOrdinal is identical to CtxSprintf except the ordinal variant of the format string for position n
+// is selected using the CLDR ordinal rules of the language of the text finder linked to the passed context.
+// n is not passed to the format, include it in args if it is to be printed.
+func CtxSprintfOrdinal(ctx context.Context, id TextID, n int, args ...interface{}) string {
+	tf := FromContext(ctx)
+
+	f, ok := FindOrdinal(tf, id, n)
+
+	if !ok {
+		f, args = missingFormat(tf, id, args)
+	}
+
+	return fmt.Sprintf(f, args...)
+}
+
+// Format renders the ICU MessageFormat pattern taken from the default text finder in the finder's language.
+// Arguments are positional, {0} being the first arg, or named by passing MessageArgs.
+// If the string is not found, or is not a valid pattern, the string version of the id is printed along with a
+// space separated %v version of each arg.
+func Format(id TextID, args ...interface{}) string {
+	return formatMessage(Default(), id, args)
+}
 
-		args = append([]interface{}{id}, args...)
+// CtxFormat renders the ICU MessageFormat pattern taken from the text finder linked to the passed
+// context in the finder's language. If the context has no finder the default finder is used.
+// Arguments are positional, {0} being the first arg, or named by passing MessageArgs.
+// If the string is not found, or is not a valid pattern, the string version of the id is printed along with a
+// space separated %v version of each arg.
+func CtxFormat(ctx context.Context, id TextID, args ...interface{}) string {
+	return formatMessage(FromContext(ctx), id, args)
+}
+
+func formatMessage(tf TextFinder, id TextID, args []interface{}) string {
+	f, ok := tf.Find(id)
+	if ok {
+		if m, err := cachedMessage(f); err == nil {
+			return m.Format(finderLanguage(tf), args...)
+		}
+
+		// invalid patterns are printed as the id and args
+		f = strings.TrimRight("%s:"+strings.Repeat("%v ", len(args)), " ")
+		return fmt.Sprintf(f, append([]interface{}{id}, args...)...)
 	}
 
-	return fmt.Errorf(f, args...)
+	f, args = missingFormat(tf, id, args)
+	return fmt.Sprintf(f, args...)
 }
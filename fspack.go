@@ -0,0 +1,129 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"golang.org/x/text/language"
+)
+
+// FSPack identifies a pack stored in an fs.FS directory tree, see RegisterFS.
+type FSPack struct {
+	// ID is the pack id the pack is registered with.
+	ID PackID
+
+	// Name is the pack's file name without the .json extension.
+	Name string
+
+	// Decode decodes the pack's text keys.
+	Decode TextIDDecoder
+}
+
+// FSPackReader reads the pack file name, in the language langTag, from fsys.  An error matching
+// fs.ErrNotExist is returned if the file does not exist.
+type FSPackReader func(fsys fs.FS, name string, langTag Tag) (*JSONPack, error)
+
+// ReadFSPacks reads the JSON packs stored as <root>/<bcp47 language>/<name>.json in fsys.
+// The language of a pack file may be omitted, if present it must match its directory.
+// The pack for the DefaultLanguage, if any, is returned first so it is used as the fallback
+// language when the packs are registered.
+func ReadFSPacks(fsys fs.FS, root, name string, decode TextIDDecoder) (JSONPacks, error) {
+	return ReadFSLanguages(fsys, root, name+".json", func(fsys fs.FS, name string, langTag Tag) (*JSONPack, error) {
+		return readFSPack(fsys, name, decode, langTag)
+	})
+}
+
+// ReadFSLanguages reads the packs stored as <root>/<language>/<file> in fsys using read, allowing
+// packs stored in other formats to be read from per language directories.  Directories are named
+// by BCP 47 language tags, which may use underscores, e.g. pt_BR, and directories without the file
+// are skipped.  The pack for the DefaultLanguage, if any, is returned first so it is used as the
+// fallback language when the packs are registered.
+func ReadFSLanguages(fsys fs.FS, root, file string, read FSPackReader) (JSONPacks, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultTag := language.MustParse(DefaultLanguage)
+	packs := make(JSONPacks, 0, len(entries))
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		tag, err := language.Parse(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("language directory %q: %w", entry.Name(), err)
+		}
+
+		p, err := read(fsys, path.Join(root, entry.Name(), file), tag)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if tag == defaultTag {
+			packs = append(JSONPacks{p}, packs...)
+		} else {
+			packs = append(packs, p)
+		}
+	}
+
+	return packs, nil
+}
+
+func readFSPack(fsys fs.FS, name string, decode TextIDDecoder, langTag Tag) (*JSONPack, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := readJSONPack(f, decode, &langTag)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return p, nil
+}
+
+// RegisterFS registers the packs stored as <root>/<bcp47 language>/<pack name>.json in fsys,
+// such as an embed.FS, with the registry.  Each pack is registered for the languages found with
+// the passed priority.  An error is returned if no files are found for a pack.
+func RegisterFS(r TextRegistry, fsys fs.FS, root string, priority Priority, packs ...FSPack) error {
+	for _, p := range packs {
+		found, err := ReadFSPacks(fsys, root, p.Name, p.Decode)
+		if err != nil {
+			return err
+		}
+
+		if len(found) == 0 {
+			return fmt.Errorf("no files found for pack %q", p.Name)
+		}
+
+		r.Register(p.ID, found.OnRegister, priority, found.Tags()...)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/text/language"
+)
+
+var testLocales = fstest.MapFS{
+	"locales/de/example.json": {Data: []byte(`{"texts": {"hello": "Hallo Welt"}}`)},
+	"locales/en/example.json": {Data: []byte(`{"language": "en", "texts": {"hello": "Hello World", "args": "Single %v"}}`)},
+	"locales/fr/example.json": {Data: []byte(frenchJSONPack)},
+	"locales/fr/other.json":   {Data: []byte(`{"texts": {"hello": "Autre"}}`)},
+	"locales/README.md":       {Data: []byte(`# locales`)},
+}
+
+func TestReadFSPacks(t *testing.T) {
+	packs, err := ReadFSPacks(testLocales, "locales", "example", decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := packs.Tags()
+	if len(tags) != 3 || tags[0] != language.English || tags[1] != language.German || tags[2] != language.French {
+		t.Error("tags", tags)
+	}
+
+	if s := packs.OnRegister(ExamplePackID, language.German)[Hello]; s != "Hallo Welt" {
+		t.Error("german", s)
+	}
+}
+
+func TestReadFSPacksErrors(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing root":      {},
+		"bad directory":     {"locales/not-a-language-x/example.json": {Data: []byte(`{}`)}},
+		"language mismatch": {"locales/de/example.json": {Data: []byte(`{"language": "fr"}`)}},
+		"bad json":          {"locales/de/example.json": {Data: []byte(`{`)}},
+	} {
+		if _, err := ReadFSPacks(fsys, "locales", "example", decodeTestTextID); err == nil {
+			t.Error("no error", name)
+		}
+	}
+}
+
+type otherPackID int
+
+func TestRegisterFS(t *testing.T) {
+	r := NewRegistry()
+
+	err := RegisterFS(r, testLocales, "locales", DefaultPriority,
+		FSPack{ID: ExamplePackID, Name: "example", Decode: decodeTestTextID},
+		FSPack{ID: otherPackID(1), Name: "other", Decode: func(key string) (TextID, error) {
+			return None, nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := r.New(language.French).Text(Hello.Plural()); s != "Bonjour les mondes" {
+		t.Error("french", s)
+	}
+
+	if s := r.New(language.French).Text(None); s != "Autre" {
+		t.Error("other", s)
+	}
+
+	if s := r.New(language.Japanese).Text(Hello); s != "Hello World" {
+		t.Error("fallback", s)
+	}
+}
+
+func TestRegisterFSMissingPack(t *testing.T) {
+	err := RegisterFS(NewRegistry(), testLocales, "locales", DefaultPriority,
+		FSPack{ID: ExamplePackID, Name: "missing", Decode: decodeTestTextID})
+	if err == nil {
+		t.Error("no error")
+	}
+
+	err = RegisterFS(NewRegistry(), testLocales, "missing", DefaultPriority,
+		FSPack{ID: ExamplePackID, Name: "example", Decode: decodeTestTextID})
+	if err == nil {
+		t.Error("no error missing root")
+	}
+}
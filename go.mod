@@ -1,10 +1,14 @@
 module github.com/nehemming/lpax
 
-go 1.16
+go 1.25.0
 
 require (
 	github.com/cloudfoundry-attic/jibber_jabber v0.0.0-20151120183258-bcc4c8345a21
+	golang.org/x/text v0.3.6
+)
+
+require (
 	github.com/cloudfoundry/jibber_jabber v0.0.0-20151120183258-bcc4c8345a21 // indirect
+	github.com/onsi/ginkgo v1.16.4 // indirect
 	github.com/onsi/gomega v1.14.0 // indirect
-	golang.org/x/text v0.3.6
 )
@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package printf parses the verbs of fmt format strings following the rules of fmt, it is shared by the
+// lpax packages converting, validating and pseudo-localizing format strings.
+package printf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Verb is a verb of a fmt format string.
+type Verb struct {
+	// Start and End are the offsets of the verb in the format, from its % to the byte following the verb.
+	Start, End int
+
+	// Verb is the verb, '%' for a literal percent, or 0 if the format ends before the verb.
+	Verb rune
+
+	// Spec is the flags, width and precision of the verb without argument indexes, e.g. "-5.2" or "*".
+	Spec string
+
+	// Arg is the zero based index of the arg formatted by the verb, following fmt's rules for explicit
+	// [n] argument indexes.  A literal percent formats no arg.
+	Arg int
+
+	// StarArgs are the zero based indexes of the args providing a * width or precision.
+	StarArgs []int
+
+	// Index is the explicit [n] argument index written immediately before the verb, zero if none.
+	Index int
+
+	// Indexed is true if the verb uses any explicit argument index.
+	Indexed bool
+
+	// BadIndex is true if an argument index is malformed or misplaced, fmt prints %!v(BADINDEX).
+	BadIndex bool
+}
+
+// Parse returns the verbs of a fmt format string in order, including literal percents.
+func Parse(format string) []Verb {
+	var verbs []Verb
+	argNum := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		v := ParseVerb(format, i, argNum)
+		verbs = append(verbs, v)
+
+		argNum = v.Arg
+		if v.Verb != '%' {
+			argNum++
+		}
+		i = v.End - 1
+	}
+
+	return verbs
+}
+
+// ParseVerb parses the verb starting with the % at start in a fmt format string, argNum is the
+// zero based index of the arg the verb formats unless it uses an explicit argument index.
+func ParseVerb(format string, start, argNum int) Verb {
+	v := Verb{Start: start}
+	i := start + 1
+
+	var spec strings.Builder
+
+	flags := i
+	for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+		i++
+	}
+	spec.WriteString(format[flags:i])
+
+	// argIndex parses an explicit [n] argument index at i, reporting if one was found
+	argIndex := func() bool {
+		if i >= len(format) || format[i] != '[' {
+			return false
+		}
+
+		v.Indexed = true
+
+		end := strings.IndexByte(format[i:], ']')
+		if end < 0 {
+			v.BadIndex = true
+			i++
+			return true
+		}
+
+		n, err := strconv.Atoi(format[i+1 : i+end])
+		if err != nil || n < 1 {
+			v.BadIndex = true
+		} else {
+			argNum, v.Index = n-1, n
+		}
+
+		i += end + 1
+		return true
+	}
+
+	// widthOrPrecision parses a number or * at i, reporting if an argument index precedes a number
+	widthOrPrecision := func() (afterIndex bool) {
+		afterIndex = argIndex()
+		if i < len(format) && format[i] == '*' {
+			v.StarArgs = append(v.StarArgs, argNum)
+			argNum++
+			spec.WriteByte('*')
+			i++
+			return false
+		}
+
+		digits := i
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+		spec.WriteString(format[digits:i])
+
+		if afterIndex && i > digits {
+			v.BadIndex = true // "%[3]2d"
+		}
+		return afterIndex
+	}
+
+	afterIndex := widthOrPrecision()
+	if i < len(format) && format[i] == '.' {
+		if afterIndex {
+			v.BadIndex = true // "%[3].2d"
+		}
+		spec.WriteByte('.')
+		i++
+		afterIndex = widthOrPrecision()
+	}
+
+	// the index of the verb may precede it or its width or precision
+	if !afterIndex && !argIndex() {
+		v.Index = 0 // any index was used by a * width or precision
+	}
+
+	v.Spec, v.Arg = spec.String(), argNum
+
+	if i >= len(format) {
+		v.End = len(format)
+		return v
+	}
+
+	verb, size := utf8.DecodeRuneInString(format[i:])
+	v.Verb, v.End = verb, i+size
+
+	return v
+}
@@ -0,0 +1,45 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		expected []Verb
+	}{
+		{"none", nil},
+		{"%d%%", []Verb{{Start: 0, End: 2, Verb: 'd'}, {Start: 2, End: 4, Verb: '%', Arg: 1}}},
+		{"a %-5.2f", []Verb{{Start: 2, End: 8, Verb: 'f', Spec: "-5.2"}}},
+		{"%[2]s %s", []Verb{
+			{Start: 0, End: 5, Verb: 's', Arg: 1, Index: 2, Indexed: true},
+			{Start: 6, End: 8, Verb: 's', Arg: 2},
+		}},
+		{"%*.[3]*[1]d", []Verb{{Start: 0, End: 11, Verb: 'd', Spec: "*.*", StarArgs: []int{0, 2}, Index: 1, Indexed: true}}},
+		{"%[3]2d", []Verb{{Start: 0, End: 6, Verb: 'd', Spec: "2", Arg: 2, Index: 3, Indexed: true, BadIndex: true}}},
+		{"%[x]d", []Verb{{Start: 0, End: 5, Verb: 'd', Indexed: true, BadIndex: true}}},
+		{"%5", []Verb{{Start: 0, End: 2, Spec: "5"}}},
+	} {
+		if verbs := Parse(tc.format); !reflect.DeepEqual(verbs, tc.expected) {
+			t.Errorf("%s: got %+v", tc.format, verbs)
+		}
+	}
+}
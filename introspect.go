@@ -0,0 +1,156 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// IntrospectRegistry is implemented by registries able to describe their registered packs, such as
+	// those created by NewRegistry.
+	IntrospectRegistry interface {
+		// Packs returns the registered packs, their languages and registrations, ordered by name.
+		Packs() []PackInfo
+
+		// PackText returns the text of a pack in a registered language, merging the text of the callbacks
+		// registered for the language in priority order.  nil is returned if the language is not registered.
+		PackText(packID PackID, langTag Tag) TextMap
+	}
+
+	// PackInfo describes a registered pack.
+	PackInfo struct {
+		// ID is the pack's id.
+		ID PackID `json:"-"`
+
+		// Name is the type qualified value of the id, e.g. messages.PackID(1).
+		Name string `json:"id"`
+
+		// Languages are the distinct languages registered for the pack, highest priority registrations first.
+		Languages []Tag `json:"languages"`
+
+		// Registrations are the calls made to Register for the pack, highest priority first.
+		Registrations []PackRegistration `json:"registrations"`
+	}
+
+	// PackRegistration describes a call to Register.
+	PackRegistration struct {
+		// Priority is the priority of the registration.
+		Priority Priority `json:"priority"`
+
+		// Languages are the languages registered.
+		Languages []Tag `json:"languages"`
+	}
+)
+
+// packName returns the type qualified value of a pack id.
+func packName(packID PackID) string {
+	return fmt.Sprintf("%T(%v)", packID, packID)
+}
+
+// Packs returns the registered packs, their languages and registrations, ordered by name.
+func (r *packRegistry) Packs() []PackInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	packs := make([]PackInfo, 0, len(r.registered))
+
+	for packID, group := range r.registered {
+		group.sort()
+
+		info := PackInfo{ID: packID, Name: packName(packID)}
+		distinct := make(map[Tag]bool)
+
+		// entries are sorted lowest priority first
+		for i := len(group.entries) - 1; i >= 0; i-- {
+			entry := group.entries[i]
+
+			info.Registrations = append(info.Registrations, PackRegistration{
+				Priority:  entry.priority,
+				Languages: append([]Tag(nil), entry.supported...),
+			})
+
+			for _, tag := range entry.supported {
+				if !distinct[tag] {
+					distinct[tag] = true
+					info.Languages = append(info.Languages, tag)
+				}
+			}
+		}
+
+		packs = append(packs, info)
+	}
+
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+
+	return packs
+}
+
+// PackText returns the text of a pack in a registered language, merging the text of the callbacks
+// registered for the language in priority order.  nil is returned if the language is not registered.
+func (r *packRegistry) PackText(packID PackID, langTag Tag) TextMap {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, found := r.registered[packID]
+	if !found {
+		return nil
+	}
+
+	group.sort()
+
+	var textMaps []TextMap
+	registered := false
+
+	for _, entry := range group.entries {
+		if !entry.supports(langTag) {
+			continue
+		}
+
+		registered = true
+		if tm := entry.callback(packID, langTag); tm != nil {
+			textMaps = append(textMaps, tm)
+		}
+	}
+
+	if !registered {
+		return nil
+	}
+
+	return NewTextMap(textMaps...)
+}
+
+// sort orders the group's entries by priority if they have changed.
+func (group *packGroup) sort() {
+	if !group.isSorted {
+		sort.Sort(group.entries)
+		group.isSorted = true
+	}
+}
+
+// supports reports if the entry was registered for the language.
+func (entry packEntry) supports(langTag Tag) bool {
+	for _, tag := range entry.supported {
+		if tag == langTag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/language"
+)
+
+type (
+	// TextIDDecoder decodes the key of a text in a pack file into its TextID.
+	TextIDDecoder func(key string) (TextID, error)
+
+	// JSONPack is a language pack loaded from a JSON document of the form
+	//
+	//	{
+	//	  "language": "fr",
+	//	  "pack": "example",
+	//	  "metadata": { "translator": "..." },
+	//	  "texts": {
+	//	    "hello": "Bonjour",
+	//	    "files": { "single": "%d fichier", "plural": "%d fichiers" },
+	//	    "items": { "one": "%d élément", "many": "%d d'éléments", "other": "%d éléments" },
+	//	    "place": { "single": "%de place", "ordinal": { "one": "%dre place" } }
+	//	  }
+	//	}
+	//
+	// A text is either a string, stored as the Single version of the key's TextID, or an object
+	// containing the single and plural versions, the CLDR plural categories zero, one, two, few,
+	// many and other, see ByCategory, and an ordinal object of ordinal categories, see ByOrdinalCategory.
+	JSONPack struct {
+		// Language is the language of the pack's texts.
+		Language Tag
+
+		// Pack is the name of the pack.
+		Pack string
+
+		// Metadata is optional information about the pack such as its translator.
+		Metadata map[string]string
+
+		// Texts are the texts of the pack.
+		Texts TextMap
+	}
+
+	// JSONPacks is a list of packs in different languages that can be registered together
+	// using r.Register(packID, packs.OnRegister, priority, packs.Tags()...).
+	JSONPacks []*JSONPack
+
+	// jsonPackDocument is the JSON document of a pack.
+	jsonPackDocument struct {
+		Language string                     `json:"language"`
+		Pack     string                     `json:"pack"`
+		Metadata map[string]string          `json:"metadata"`
+		Texts    map[string]json.RawMessage `json:"texts"`
+	}
+)
+
+// ReadJSONPack reads a JSON pack document, decoding each text's key using decode.
+func ReadJSONPack(r io.Reader, decode TextIDDecoder) (*JSONPack, error) {
+	return readJSONPack(r, decode, nil)
+}
+
+// readJSONPack reads a JSON pack document.  If langTag is not nil the document's language
+// is optional but must match langTag if present.
+func readJSONPack(r io.Reader, decode TextIDDecoder, langTag *Tag) (*JSONPack, error) {
+	var doc jsonPackDocument
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	tag, err := jsonPackLanguage(doc.Language, langTag)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make(TextMap, len(doc.Texts))
+	for key, raw := range doc.Texts {
+		id, err := decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("text %q: %w", key, err)
+		}
+
+		if err := addJSONText(texts, id, raw); err != nil {
+			return nil, fmt.Errorf("text %q: %w", key, err)
+		}
+	}
+
+	return &JSONPack{
+		Language: tag,
+		Pack:     doc.Pack,
+		Metadata: doc.Metadata,
+		Texts:    texts,
+	}, nil
+}
+
+// jsonPackLanguage parses the language of a document, defaulting to langTag if not nil.
+func jsonPackLanguage(lang string, langTag *Tag) (Tag, error) {
+	if lang == "" {
+		if langTag == nil {
+			return Tag{}, errors.New("pack has no language")
+		}
+		return *langTag, nil
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return Tag{}, fmt.Errorf("pack language %q: %w", lang, err)
+	}
+
+	if langTag != nil && tag != *langTag {
+		return Tag{}, fmt.Errorf("pack language %q does not match %q", lang, langTag)
+	}
+
+	return tag, nil
+}
+
+// addJSONText adds a string text or an object of text variants to texts.
+func addJSONText(texts TextMap, id TextID, raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		texts[id.Single()] = s
+		return nil
+	}
+
+	var variants map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &variants); err != nil {
+		return errors.New("text must be a string or an object of variants")
+	}
+
+	for name, v := range variants {
+		if name == "ordinal" {
+			var ordinals map[string]string
+			if err := json.Unmarshal(v, &ordinals); err != nil {
+				return errors.New("ordinal must be an object of categories")
+			}
+
+			for c, text := range ordinals {
+				category, ok := pluralCategoryByName(c)
+				if !ok {
+					return fmt.Errorf("unknown ordinal category %q", c)
+				}
+				texts[ByOrdinalCategory(id, category)] = text
+			}
+			continue
+		}
+
+		if err := json.Unmarshal(v, &s); err != nil {
+			return fmt.Errorf("variant %q must be a string", name)
+		}
+
+		key, err := variantTextID(id, name)
+		if err != nil {
+			return err
+		}
+		texts[key] = s
+	}
+
+	return nil
+}
+
+// variantTextID returns the key of a named variant of a text.
+func variantTextID(id TextID, name string) (TextID, error) {
+	switch name {
+	case "single":
+		return id.Single(), nil
+	case "plural":
+		return id.Plural(), nil
+	}
+
+	category, ok := pluralCategoryByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown variant %q", name)
+	}
+	return ByCategory(id, category), nil
+}
+
+// OnRegister returns the texts of the pack in the language langTag.  If more than one pack
+// has the language their texts are merged in order.
+// OnRegister has the OnRegister signature allowing it to be passed to TextRegistry Register.
+func (packs JSONPacks) OnRegister(packID PackID, langTag Tag) TextMap {
+	var tm TextMap
+	for _, p := range packs {
+		if p.Language == langTag {
+			tm = NewTextMap(tm, p.Texts)
+		}
+	}
+	return tm
+}
+
+// Tags returns the distinct languages of the packs in order.
+func (packs JSONPacks) Tags() []Tag {
+	langTags := make([]Tag, 0, len(packs))
+	distinct := make(map[Tag]bool)
+
+	for _, p := range packs {
+		if !distinct[p.Language] {
+			distinct[p.Language] = true
+			langTags = append(langTags, p.Language)
+		}
+	}
+
+	return langTags
+}
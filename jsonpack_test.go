@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+const frenchJSONPack = `{
+	"language": "fr",
+	"pack": "example",
+	"metadata": { "translator": "Anne" },
+	"texts": {
+		"hello": { "single": "Bonjour le monde", "plural": "Bonjour les mondes" },
+		"args": "Unique %v",
+		"none": { "many": "%d de riens", "ordinal": { "one": "%dre" } }
+	}
+}`
+
+func decodeTestTextID(key string) (TextID, error) {
+	switch key {
+	case "none":
+		return None, nil
+	case "hello":
+		return Hello, nil
+	case "args":
+		return Args, nil
+	}
+	return nil, errors.New("unknown key")
+}
+
+func TestReadJSONPack(t *testing.T) {
+	p, err := ReadJSONPack(strings.NewReader(frenchJSONPack), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Language != language.French || p.Pack != "example" || p.Metadata["translator"] != "Anne" {
+		t.Error("pack", p)
+	}
+
+	expected := TextMap{
+		Hello:                              "Bonjour le monde",
+		Hello.Plural():                     "Bonjour les mondes",
+		Args:                               "Unique %v",
+		ByCategory(None, PluralMany):       "%d de riens",
+		ByOrdinalCategory(None, PluralOne): "%dre",
+	}
+
+	if len(p.Texts) != len(expected) {
+		t.Error("len", len(p.Texts))
+	}
+
+	for k, v := range expected {
+		if p.Texts[k] != v {
+			t.Error(k, p.Texts[k])
+		}
+	}
+}
+
+func TestReadJSONPackErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{`,
+		`{"texts": {}}`,
+		`{"language": "not a language"}`,
+		`{"language": "fr", "texts": {"unknown": "x"}}`,
+		`{"language": "fr", "texts": {"hello": 1}}`,
+		`{"language": "fr", "texts": {"hello": {"single": 1}}}`,
+		`{"language": "fr", "texts": {"hello": {"lots": "x"}}}`,
+		`{"language": "fr", "texts": {"hello": {"ordinal": "x"}}}`,
+		`{"language": "fr", "texts": {"hello": {"ordinal": {"lots": "x"}}}}`,
+	} {
+		if _, err := ReadJSONPack(strings.NewReader(doc), decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestRegisterJSONPacks(t *testing.T) {
+	fr, err := ReadJSONPack(strings.NewReader(frenchJSONPack), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en := &JSONPack{Language: language.English, Texts: pack}
+	packs := JSONPacks{en, fr, &JSONPack{Language: language.French, Texts: TextMap{Args: "Autre %v"}}}
+
+	if tags := packs.Tags(); len(tags) != 2 || tags[0] != language.English || tags[1] != language.French {
+		t.Error("tags", tags)
+	}
+
+	r := NewRegistry().Register(ExamplePackID, packs.OnRegister, DefaultPriority, packs.Tags()...)
+
+	tf := r.New(language.French)
+	if s := tf.Text(Hello.Plural()); s != "Bonjour les mondes" {
+		t.Error("hello", s)
+	}
+
+	if s := tf.Text(Args); s != "Autre %v" {
+		t.Error("args", s)
+	}
+
+	if s := r.New(language.English).Text(Hello); s != "Hello World" {
+		t.Error("english", s)
+	}
+}
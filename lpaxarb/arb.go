@@ -0,0 +1,289 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxarb reads and writes Flutter Application Resource Bundle (ARB) files such as app_en.arb.
+//
+// ARB messages are ICU MessageFormat patterns and are stored unchanged, they can be rendered
+// using lpax.Format.  Each message is the Single version of its key's TextID, the Plural
+// version is exchanged using the key with PluralSuffix appended.
+package lpaxarb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+const (
+	// PluralSuffix is appended to the key of Plural texts.
+	PluralSuffix = "_plural"
+
+	localeKey = "@@locale"
+)
+
+type (
+	// TextIDEncoder encodes a Single TextID as a message key.
+	// The encoding must be reversible by the TextIDDecoder used to read the file back.
+	TextIDEncoder func(id lpax.TextID) string
+
+	// Placeholder describes a placeholder of a message.
+	Placeholder struct {
+		Type               string                 `json:"type,omitempty"`
+		Description        string                 `json:"description,omitempty"`
+		Example            string                 `json:"example,omitempty"`
+		Format             string                 `json:"format,omitempty"`
+		OptionalParameters map[string]interface{} `json:"optionalParameters,omitempty"`
+	}
+
+	// Resource is the @key metadata of a message.
+	Resource struct {
+		Description  string                 `json:"description,omitempty"`
+		Type         string                 `json:"type,omitempty"`
+		Context      string                 `json:"context,omitempty"`
+		Placeholders map[string]Placeholder `json:"placeholders,omitempty"`
+	}
+
+	// File is an ARB file.
+	File struct {
+		// Locale is the @@locale of the file.
+		Locale lpax.Tag
+
+		// Attributes are the file's other global @@ attributes, e.g. @@last_modified, keyed without the @@ prefix.
+		Attributes map[string]string
+
+		// Texts are the messages of the file.
+		Texts lpax.TextMap
+
+		// Resources are the @key metadata of the messages, keyed by the Single or Plural TextID of the message.
+		Resources map[lpax.TextID]Resource
+	}
+)
+
+// Read reads an ARB file decoding each message key using decode.  Keys ending in PluralSuffix are
+// decoded without the suffix and stored as the Plural version of the TextID.  The file must have
+// a @@locale unless langTag is not language.Und, in which case the locale, if present, must match langTag.
+func Read(r io.Reader, langTag lpax.Tag, decode lpax.TextIDDecoder) (*File, error) {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Locale:    langTag,
+		Texts:     make(lpax.TextMap, len(doc)),
+		Resources: make(map[lpax.TextID]Resource),
+	}
+
+	for key, raw := range doc {
+		if err := f.add(key, raw, decode); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if f.Locale == language.Und {
+		return nil, errors.New("ARB file has no @@locale")
+	}
+
+	return f, nil
+}
+
+// add adds a global attribute, message or resource to the file.
+func (f *File) add(key string, raw json.RawMessage, decode lpax.TextIDDecoder) error {
+	switch {
+	case strings.HasPrefix(key, "@@"):
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return errors.New("attribute must be a string")
+		}
+		return f.setAttribute(key, s)
+
+	case strings.HasPrefix(key, "@"):
+		id, err := decodeKey(key[1:], decode)
+		if err != nil {
+			return err
+		}
+
+		var res Resource
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return err
+		}
+		f.Resources[id] = res
+		return nil
+	}
+
+	id, err := decodeKey(key, decode)
+	if err != nil {
+		return err
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return errors.New("message must be a string")
+	}
+	f.Texts[id] = s
+	return nil
+}
+
+// setAttribute sets the locale or another global attribute.
+func (f *File) setAttribute(key, value string) error {
+	if key != localeKey {
+		if f.Attributes == nil {
+			f.Attributes = make(map[string]string)
+		}
+		f.Attributes[key[2:]] = value
+		return nil
+	}
+
+	// ARB locales may use underscores, e.g. en_US
+	tag, err := language.Parse(strings.ReplaceAll(value, "_", "-"))
+	if err != nil {
+		return err
+	}
+
+	if f.Locale != language.Und && tag != f.Locale {
+		return fmt.Errorf("locale %s does not match %s", tag, f.Locale)
+	}
+
+	f.Locale = tag
+	return nil
+}
+
+// decodeKey decodes a message key into the Single or Plural version of its TextID.
+func decodeKey(key string, decode lpax.TextIDDecoder) (lpax.TextID, error) {
+	if single := strings.TrimSuffix(key, PluralSuffix); single != key {
+		id, err := decode(single)
+		if err != nil {
+			return nil, err
+		}
+		return id.Plural(), nil
+	}
+
+	id, err := decode(key)
+	if err != nil {
+		return nil, err
+	}
+	return id.Single(), nil
+}
+
+// Write writes the file in ARB format, starting with its @@locale and global attributes followed by
+// the messages ordered by key, each followed by its @key resource.  Plural category variants are not
+// written.  If encode is nil the String version of each id is used.
+func Write(w io.Writer, f *File, encode TextIDEncoder) error {
+	if encode == nil {
+		encode = func(id lpax.TextID) string { return id.String() }
+	}
+
+	type message struct {
+		key string
+		id  lpax.TextID
+	}
+
+	messages := make([]message, 0, len(f.Texts))
+	for id := range f.Texts {
+		single := id.Single()
+		switch {
+		case id == single:
+			messages = append(messages, message{encode(single), id})
+		case id == id.Plural():
+			messages = append(messages, message{encode(single) + PluralSuffix, id})
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].key < messages[j].key })
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+
+	sep := "\n"
+	writeMember := func(key string, value interface{}) error {
+		k, err := marshal(key)
+		if err != nil {
+			return err
+		}
+
+		b, err := marshal(value)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&buf, "%s  %s: %s", sep, k, b)
+		sep = ",\n"
+		return nil
+	}
+
+	if err := writeMember(localeKey, f.Locale.String()); err != nil {
+		return err
+	}
+
+	attributes := make([]string, 0, len(f.Attributes))
+	for k := range f.Attributes {
+		attributes = append(attributes, k)
+	}
+	sort.Strings(attributes)
+
+	for _, k := range attributes {
+		if err := writeMember("@@"+k, f.Attributes[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range messages {
+		if err := writeMember(m.key, f.Texts[m.id]); err != nil {
+			return err
+		}
+
+		if res, ok := f.Resources[m.id]; ok {
+			if err := writeMember("@"+m.key, res); err != nil {
+				return err
+			}
+		}
+	}
+
+	buf.WriteString("\n}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// marshal returns the indented JSON encoding of v without escaping HTML characters.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("  ", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// JSONPack returns the file as a pack that can be registered using lpax.JSONPacks.
+func (f *File) JSONPack() *lpax.JSONPack {
+	return &lpax.JSONPack{
+		Language: f.Locale,
+		Metadata: f.Attributes,
+		Texts:    f.Texts,
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxarb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type testTextID string
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID("-" + strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) String() string {
+	return string(id)
+}
+
+func decodeTestTextID(key string) (lpax.TextID, error) {
+	if key == "" || strings.HasPrefix(key, "-") {
+		return nil, errors.New("bad key")
+	}
+	return testTextID(key), nil
+}
+
+const appEn = `{
+  "@@locale": "en",
+  "@@last_modified": "2021-05-01T12:00:00Z",
+  "files": "%d file",
+  "files_plural": "%d files <b>",
+  "helloWorld": "Hello World!",
+  "@helloWorld": {
+    "description": "The conventional newborn programmer greeting"
+  },
+  "nWombats": "{count, plural, =0 {no wombats} one {1 wombat} other {{count} wombats}}",
+  "@nWombats": {
+    "description": "A plural message",
+    "placeholders": {
+      "count": {
+        "type": "num",
+        "format": "compact"
+      }
+    }
+  }
+}
+`
+
+func TestRead(t *testing.T) {
+	f, err := Read(strings.NewReader(appEn), language.Und, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Locale != language.English || f.Attributes["last_modified"] != "2021-05-01T12:00:00Z" {
+		t.Error("attributes", f.Locale, f.Attributes)
+	}
+
+	if len(f.Texts) != 4 || f.Texts[testTextID("helloWorld")] != "Hello World!" || f.Texts[testTextID("-files")] != "%d files <b>" {
+		t.Error("texts", f.Texts)
+	}
+
+	res := f.Resources[testTextID("nWombats")]
+	if res.Description != "A plural message" || res.Placeholders["count"].Type != "num" || res.Placeholders["count"].Format != "compact" {
+		t.Error("resource", res)
+	}
+
+	s, found := lpax.NewLanguageFinder(f.Locale, f.Texts).Find(testTextID("nWombats"))
+	if !found {
+		t.Fatal("not found")
+	}
+
+	if m, err := lpax.ParseMessage(s); err != nil || m.Format(f.Locale, lpax.MessageArgs{"count": 5}) != "5 wombats" {
+		t.Error("format", err)
+	}
+}
+
+func TestReadLocale(t *testing.T) {
+	if _, err := Read(strings.NewReader(appEn), language.French, decodeTestTextID); err == nil {
+		t.Error("locale mismatch")
+	}
+
+	f, err := Read(strings.NewReader(`{"@@locale": "pt_BR"}`), language.Und, decodeTestTextID)
+	if err != nil || f.Locale != language.BrazilianPortuguese {
+		t.Error("underscore locale", f, err)
+	}
+
+	f, err = Read(strings.NewReader(`{"hello": "Bonjour"}`), language.French, decodeTestTextID)
+	if err != nil || f.Locale != language.French || f.Texts[testTextID("hello")] != "Bonjour" {
+		t.Error("default locale", f, err)
+	}
+
+	if _, err := Read(strings.NewReader(`{"hello": "Bonjour"}`), language.Und, decodeTestTextID); err == nil {
+		t.Error("no locale")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{`,
+		`{"@@locale": "en", "hello": 1}`,
+		`{"@@locale": "en", "@hello": "x"}`,
+		`{"@@locale": 1}`,
+		`{"@@locale": "en", "-bad": "x"}`,
+		`{"@@locale": "en", "@-bad": {}}`,
+		`{"@@locale": "en", "-bad_plural": "x"}`,
+		`{"@@locale": "!!"}`,
+	} {
+		if _, err := Read(strings.NewReader(doc), language.Und, decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	f, err := Read(strings.NewReader(appEn), language.Und, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Texts[lpax.ByCategory(testTextID("files"), lpax.PluralFew)] = "not written"
+
+	var buf bytes.Buffer
+	if err := Write(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != appEn {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), appEn)
+	}
+}
+
+func TestJSONPack(t *testing.T) {
+	f, err := Read(strings.NewReader(appEn), language.Und, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packs := lpax.JSONPacks{f.JSONPack()}
+	if tm := packs.OnRegister(nil, language.English); tm[testTextID("files")] != "%d file" {
+		t.Error("OnRegister", tm)
+	}
+}
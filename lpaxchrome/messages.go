@@ -0,0 +1,373 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxchrome reads and writes Chrome extension i18n messages.json files.
+//
+// Chrome messages refer to the substitutions passed to chrome.i18n.getMessage as $1 to $9, either
+// directly or through named $placeholder$ references.  When read, these are converted to the explicit
+// argument index fmt verbs %[1]v to %[9]v, allowing the texts to be used with lpax.Sprintf.  When written,
+// each fmt verb becomes a named placeholder whose content is the verb's substitution and whose example
+// is the verb, e.g. %.2f.  A substitution formatted by different verbs has a placeholder for each verb.  A placeholder whose content is a single substitution and whose example is a
+// fmt verb is read back as that verb, so verbs survive a round trip.
+//
+// Each message is the Single version of its key's TextID, the Plural version is exchanged using the key
+// with PluralSuffix appended.
+package lpaxchrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nehemming/lpax"
+	"github.com/nehemming/lpax/internal/printf"
+	"golang.org/x/text/language"
+)
+
+const (
+	// PluralSuffix is appended to the key of Plural texts.
+	PluralSuffix = "_plural"
+
+	// FileName is the name of the messages file in each locale directory.
+	FileName = "messages.json"
+)
+
+type (
+	// TextIDEncoder encodes a Single TextID as a message key.
+	// The encoding must be reversible by the TextIDDecoder used to read the file back.
+	TextIDEncoder func(id lpax.TextID) string
+
+	// Placeholder is a named placeholder of a message.
+	Placeholder struct {
+		Content string `json:"content"`
+		Example string `json:"example,omitempty"`
+	}
+
+	// message is a message of a messages.json file.
+	message struct {
+		Message      string                 `json:"message"`
+		Description  string                 `json:"description,omitempty"`
+		Placeholders map[string]Placeholder `json:"placeholders,omitempty"`
+	}
+
+	// File is a messages.json file.
+	File struct {
+		// Locale is the language of the file, taken from its _locales directory.
+		Locale lpax.Tag
+
+		// Texts are the messages of the file converted to fmt format.
+		Texts lpax.TextMap
+
+		// Descriptions are the descriptions of the messages, keyed by the Single or Plural TextID of the message.
+		Descriptions map[lpax.TextID]string
+	}
+)
+
+// LocaleName returns the _locales directory name of a language, e.g. en_US.
+func LocaleName(langTag lpax.Tag) string {
+	return strings.ReplaceAll(langTag.String(), "-", "_")
+}
+
+// ParseLocale parses a _locales directory name.
+func ParseLocale(name string) (lpax.Tag, error) {
+	return language.Parse(strings.ReplaceAll(name, "_", "-"))
+}
+
+// Read reads a messages.json file in the language langTag, decoding each message key using decode.
+// Keys ending in PluralSuffix are decoded without the suffix and stored as the Plural version of the TextID.
+func Read(r io.Reader, langTag lpax.Tag, decode lpax.TextIDDecoder) (*File, error) {
+	var doc map[string]message
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Locale:       langTag,
+		Texts:        make(lpax.TextMap, len(doc)),
+		Descriptions: make(map[lpax.TextID]string),
+	}
+
+	for key, m := range doc {
+		id, err := decodeKey(key, decode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		text, err := toFormat(m.Message, m.Placeholders)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		f.Texts[id] = text
+		if m.Description != "" {
+			f.Descriptions[id] = m.Description
+		}
+	}
+
+	return f, nil
+}
+
+// decodeKey decodes a message key into the Single or Plural version of its TextID.
+func decodeKey(key string, decode lpax.TextIDDecoder) (lpax.TextID, error) {
+	if single := strings.TrimSuffix(key, PluralSuffix); single != key {
+		id, err := decode(single)
+		if err != nil {
+			return nil, err
+		}
+		return id.Plural(), nil
+	}
+
+	id, err := decode(key)
+	if err != nil {
+		return nil, err
+	}
+	return id.Single(), nil
+}
+
+// toFormat converts a Chrome message to fmt format.
+func toFormat(msg string, placeholders map[string]Placeholder) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		switch {
+		case c == '%':
+			b.WriteString("%%")
+
+		case c != '$' || i+1 == len(msg):
+			b.WriteByte(c)
+
+		case msg[i+1] == '$':
+			b.WriteByte('$')
+			i++
+
+		case msg[i+1] >= '1' && msg[i+1] <= '9':
+			fmt.Fprintf(&b, "%%[%c]v", msg[i+1])
+			i++
+
+		default:
+			end := strings.IndexByte(msg[i+1:], '$')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+
+			name := msg[i+1 : i+1+end]
+			p, ok := findPlaceholder(placeholders, name)
+			if !ok {
+				return "", fmt.Errorf("unknown placeholder %q", name)
+			}
+
+			content, err := placeholderFormat(p)
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(content)
+			i += end + 1
+		}
+	}
+
+	return b.String(), nil
+}
+
+// placeholderFormat converts the content of a placeholder to fmt format.  Content that is a single
+// substitution uses the placeholder's example as its verb if the example is a fmt verb.
+func placeholderFormat(p Placeholder) (string, error) {
+	c := p.Content
+	if len(c) == 2 && c[0] == '$' && c[1] >= '1' && c[1] <= '9' && isVerbExample(p.Example) {
+		verb := len(p.Example) - 1
+		return fmt.Sprintf("%s[%c]%s", p.Example[:verb], c[1], p.Example[verb:]), nil
+	}
+
+	// placeholder content may contain substitutions but not further placeholders
+	return toFormat(c, nil)
+}
+
+// isVerbExample reports if a placeholder example is a single fmt verb formatting an arg without an
+// argument index or * width or precision, e.g. %.2f.
+func isVerbExample(example string) bool {
+	if !strings.HasPrefix(example, "%") {
+		return false
+	}
+
+	v := printf.ParseVerb(example, 0, 0)
+	return v.End == len(example) && supportedVerb(v) && !v.Indexed && utf8.RuneLen(v.Verb) == 1
+}
+
+// supportedVerb reports if a verb formats an arg and has no * width or precision, which would take
+// further args.
+func supportedVerb(v printf.Verb) bool {
+	return v.Verb != 0 && v.Verb != '%' && !v.BadIndex && len(v.StarArgs) == 0
+}
+
+// findPlaceholder finds a placeholder by its case insensitive name.
+func findPlaceholder(placeholders map[string]Placeholder, name string) (Placeholder, bool) {
+	for k, p := range placeholders {
+		if strings.EqualFold(k, name) {
+			return p, true
+		}
+	}
+	return Placeholder{}, false
+}
+
+// fromFormat converts a fmt format string to a Chrome message with a placeholder for each verb.
+// Verbs refer to substitutions $1 to $9 by their position or explicit argument index.
+func fromFormat(format string) (message, error) {
+	var b strings.Builder
+	m := message{}
+	argNum := 0
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '$':
+			b.WriteString("$$")
+			continue
+		case c != '%':
+			b.WriteByte(c)
+			continue
+		}
+
+		v := printf.ParseVerb(format, i, argNum)
+		i = v.End - 1
+
+		switch {
+		case v.Verb == 0:
+			return m, errors.New("incomplete verb")
+		case v.BadIndex:
+			return m, errors.New("bad argument index")
+		case v.Verb == '%':
+			b.WriteByte('%')
+			continue
+		case !supportedVerb(v):
+			return m, fmt.Errorf("verb %q is not supported", format[v.Start:v.End])
+		}
+
+		argNum = v.Arg + 1
+		if argNum > 9 {
+			return m, fmt.Errorf("argument %d is outside $1 to $9", argNum)
+		}
+
+		if m.Placeholders == nil {
+			m.Placeholders = make(map[string]Placeholder)
+		}
+
+		p := Placeholder{Content: fmt.Sprintf("$%d", argNum), Example: "%" + v.Spec + string(v.Verb)}
+		name := placeholderName(m.Placeholders, p)
+		m.Placeholders[name] = p
+
+		fmt.Fprintf(&b, "$%s$", strings.ToUpper(name))
+	}
+
+	m.Message = b.String()
+	return m, nil
+}
+
+// placeholderName returns the name of the placeholder p, argN for substitution $N, with a _2, _3 etc.
+// suffix when the substitution is formatted by more than one verb.
+func placeholderName(placeholders map[string]Placeholder, p Placeholder) string {
+	name := "arg" + p.Content[1:]
+
+	for n := 2; ; n++ {
+		if existing, ok := placeholders[name]; !ok || existing == p {
+			return name
+		}
+		name = fmt.Sprintf("arg%s_%d", p.Content[1:], n)
+	}
+}
+
+// Write writes the file in messages.json format ordered by key.  Plural category variants are not
+// written.  If encode is nil the String version of each id is used.
+func Write(w io.Writer, f *File, encode TextIDEncoder) error {
+	if encode == nil {
+		encode = func(id lpax.TextID) string { return id.String() }
+	}
+
+	doc := make(map[string]message, len(f.Texts))
+	for id, text := range f.Texts {
+		single := id.Single()
+
+		var key string
+		switch {
+		case id == single:
+			key = encode(single)
+		case id == id.Plural():
+			key = encode(single) + PluralSuffix
+		default:
+			continue
+		}
+
+		m, err := fromFormat(text)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		m.Description = f.Descriptions[id]
+		doc[key] = m
+	}
+
+	// encoding/json writes map keys in order
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// JSONPack returns the file as a pack that can be registered using lpax.JSONPacks.
+func (f *File) JSONPack() *lpax.JSONPack {
+	return &lpax.JSONPack{Language: f.Locale, Texts: f.Texts}
+}
+
+// ReadLocales reads the <root>/<locale>/messages.json files in fsys, where root is
+// typically _locales.  The pack for the lpax.DefaultLanguage, if any, is returned first
+// so it is used as the fallback language when the packs are registered.
+func ReadLocales(fsys fs.FS, root string, decode lpax.TextIDDecoder) (lpax.JSONPacks, error) {
+	return lpax.ReadFSLanguages(fsys, root, FileName, func(fsys fs.FS, name string, langTag lpax.Tag) (*lpax.JSONPack, error) {
+		f, err := readLocale(fsys, name, langTag, decode)
+		if err != nil {
+			return nil, err
+		}
+		return f.JSONPack(), nil
+	})
+}
+
+func readLocale(fsys fs.FS, name string, langTag lpax.Tag, decode lpax.TextIDDecoder) (*File, error) {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := Read(r, langTag, decode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return f, nil
+}
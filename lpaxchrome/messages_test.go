@@ -0,0 +1,239 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxchrome
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type testTextID string
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID("-" + strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) String() string {
+	return string(id)
+}
+
+func decodeTestTextID(key string) (lpax.TextID, error) {
+	if key == "" || strings.HasPrefix(key, "-") {
+		return nil, errors.New("bad key")
+	}
+	return testTextID(key), nil
+}
+
+const messagesEn = `{
+  "greeting": {
+    "message": "Hello $USER$, you owe $$5 or 100%!",
+    "description": "Greets the user",
+    "placeholders": {
+      "user": { "content": "$1", "example": "Cira" }
+    }
+  },
+  "files": { "message": "$1 file" },
+  "files_plural": { "message": "$1 files in $DIR$", "placeholders": { "dir": { "content": "$2" } } }
+}`
+
+func TestRead(t *testing.T) {
+	f, err := Read(strings.NewReader(messagesEn), language.English, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Locale != language.English || f.Descriptions[testTextID("greeting")] != "Greets the user" {
+		t.Error("file", f)
+	}
+
+	text := f.Texts[testTextID("greeting")]
+	if text != "Hello %[1]v, you owe $5 or 100%%!" {
+		t.Error("greeting", text)
+	}
+
+	if s := fmt.Sprintf(text, "Cira"); s != "Hello Cira, you owe $5 or 100%!" {
+		t.Error("sprintf", s)
+	}
+
+	if f.Texts[testTextID("-files")] != "%[1]v files in %[2]v" {
+		t.Error("plural", f.Texts)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{`,
+		`{"-bad": {"message": "x"}}`,
+		`{"bad": {"message": "$NONE$"}}`,
+	} {
+		if _, err := Read(strings.NewReader(doc), language.English, decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	f := &File{
+		Locale: language.English,
+		Texts: lpax.TextMap{
+			testTextID("greeting"):                               "Hello %s, $5 or 100%%",
+			testTextID("-files"):                                 "%[2]s has %5.2[1]f files",
+			lpax.ByCategory(testTextID("files"), lpax.PluralFew): "not written",
+		},
+		Descriptions: map[lpax.TextID]string{testTextID("greeting"): "Greets <the> user"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "files_plural": {
+    "message": "$ARG2$ has $ARG1$ files",
+    "placeholders": {
+      "arg1": {
+        "content": "$1",
+        "example": "%5.2f"
+      },
+      "arg2": {
+        "content": "$2",
+        "example": "%s"
+      }
+    }
+  },
+  "greeting": {
+    "message": "Hello $ARG1$, $$5 or 100%",
+    "description": "Greets <the> user",
+    "placeholders": {
+      "arg1": {
+        "content": "$1",
+        "example": "%s"
+      }
+    }
+  }
+}
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	read, err := Read(&buf, language.English, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verbs are preserved by the placeholder examples
+	if read.Texts[testTextID("greeting")] != "Hello %[1]s, $5 or 100%%" || read.Texts[testTextID("-files")] != "%[2]s has %5.2[1]f files" {
+		t.Error("round trip", read.Texts)
+	}
+
+	if s := fmt.Sprintf(read.Texts[testTextID("-files")], 2.5, "disk"); s != "disk has  2.50 files" {
+		t.Error("sprintf", s)
+	}
+}
+
+func TestWriteSameArgVerbs(t *testing.T) {
+	text := "%[1]d items, %[1]q, %[1]d again"
+	f := &File{Locale: language.English, Texts: lpax.TextMap{testTextID("items"): text}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `"message": "$ARG1$ items, $ARG1_2$, $ARG1$ again"`) {
+		t.Error("message", buf.String())
+	}
+
+	read, err := Read(&buf, language.English, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := read.Texts[testTextID("items")]; s != text {
+		t.Error("round trip", s)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	for _, text := range []string{"%", "%[1", "%[x]d", "%[10]d", "%5", "%*d"} {
+		f := &File{Texts: lpax.TextMap{testTextID("bad"): text}}
+		if err := Write(&bytes.Buffer{}, f, nil); err == nil {
+			t.Error("no error", text)
+		}
+	}
+}
+
+func TestLocaleName(t *testing.T) {
+	if name := LocaleName(language.BrazilianPortuguese); name != "pt_BR" {
+		t.Error("name", name)
+	}
+
+	if tag, err := ParseLocale("pt_BR"); err != nil || tag != language.BrazilianPortuguese {
+		t.Error("parse", tag, err)
+	}
+}
+
+func TestReadLocales(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_locales/fr/messages.json":    {Data: []byte(`{"files": {"message": "$1 fichier"}}`)},
+		"_locales/en/messages.json":    {Data: []byte(messagesEn)},
+		"_locales/pt_BR/messages.json": {Data: []byte(`{"files": {"message": "$1 arquivo"}}`)},
+		"_locales/de/readme.txt":       {Data: []byte("no messages")},
+		"_locales/manifest.json":       {Data: []byte("{}")},
+	}
+
+	packs, err := ReadLocales(fsys, "_locales", decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packs) != 3 || packs[0].Language != language.English || packs[1].Language != language.French ||
+		packs[2].Language != language.BrazilianPortuguese {
+		t.Fatal("packs", packs)
+	}
+
+	if tm := packs.OnRegister(nil, language.French); tm[testTextID("files")] != "%[1]v fichier" {
+		t.Error("OnRegister", tm)
+	}
+
+	fsys["_locales/es/messages.json"] = &fstest.MapFile{Data: []byte(`{`)}
+	if _, err := ReadLocales(fsys, "_locales", decodeTestTextID); err == nil {
+		t.Error("bad file")
+	}
+
+	fsys["_locales/!!/messages.json"] = &fstest.MapFile{Data: []byte(`{}`)}
+	if _, err := ReadLocales(fsys, "_locales", decodeTestTextID); err == nil {
+		t.Error("bad locale")
+	}
+
+	if _, err := ReadLocales(fsys, "missing", decodeTestTextID); err == nil {
+		t.Error("missing root")
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const (
+	// moMagic is the magic number at the start of a .mo file.
+	moMagic = 0x950412de
+
+	// moHeaderSize is the size of the fixed .mo file header.
+	moHeaderSize = 28
+
+	// contextSeparator separates the msgctxt from the msgid in .mo files.
+	contextSeparator = "\x04"
+
+	// pluralSeparator separates the plural forms in .mo files.
+	pluralSeparator = "\x00"
+)
+
+// ReadMO reads a binary .mo file.  Both byte orders are supported.
+func ReadMO(r io.Reader) (*File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < moHeaderSize {
+		return nil, errors.New("mo file too short")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data) != moMagic {
+		order = binary.BigEndian
+		if order.Uint32(data) != moMagic {
+			return nil, errors.New("invalid mo file magic number")
+		}
+	}
+
+	count := order.Uint32(data[8:])
+	origTable := order.Uint32(data[12:])
+	transTable := order.Uint32(data[16:])
+
+	// check the tables fit before reading, a corrupt count would otherwise loop up to 2^32 times
+	for _, table := range []uint32{origTable, transTable} {
+		if uint64(table)+uint64(count)*8 > uint64(len(data)) {
+			return nil, errors.New("mo table out of range")
+		}
+	}
+
+	f := &File{}
+	for i := uint32(0); i < count; i++ {
+		orig, err := moString(data, order, origTable+i*8)
+		if err != nil {
+			return nil, err
+		}
+
+		trans, err := moString(data, order, transTable+i*8)
+		if err != nil {
+			return nil, err
+		}
+
+		if orig == "" {
+			f.Header = parseHeader(trans)
+			continue
+		}
+
+		f.Entries = append(f.Entries, moEntry(orig, trans))
+	}
+
+	return f, nil
+}
+
+// moString reads the string described by the table entry at offset.
+func moString(data []byte, order binary.ByteOrder, offset uint32) (string, error) {
+	if uint64(offset)+8 > uint64(len(data)) {
+		return "", errors.New("mo table out of range")
+	}
+
+	length := order.Uint32(data[offset:])
+	start := order.Uint32(data[offset+4:])
+
+	if uint64(start)+uint64(length) > uint64(len(data)) {
+		return "", errors.New("mo string out of range")
+	}
+
+	return string(data[start : start+length]), nil
+}
+
+// moEntry creates an entry from an original and translated .mo string.
+func moEntry(orig, trans string) *Entry {
+	e := &Entry{}
+
+	if i := strings.Index(orig, contextSeparator); i >= 0 {
+		e.Context, orig = orig[:i], orig[i+1:]
+	}
+
+	ids := strings.SplitN(orig, pluralSeparator, 2)
+	e.ID = ids[0]
+	if len(ids) == 2 {
+		e.IDPlural = ids[1]
+		e.Str = strings.Split(trans, pluralSeparator)
+	} else {
+		e.Str = []string{trans}
+	}
+
+	return e
+}
+
+// WriteMO writes the file in little endian binary .mo format.
+// As with msgfmt fuzzy entries are not written.
+func WriteMO(w io.Writer, f *File) error {
+	type pair struct{ orig, trans string }
+
+	pairs := make([]pair, 0, len(f.Entries)+1)
+	if len(f.Header) > 0 {
+		pairs = append(pairs, pair{"", f.Header.String()})
+	}
+
+	for _, e := range f.Entries {
+		if e.Fuzzy() {
+			continue
+		}
+
+		orig := e.ID
+		if e.IDPlural != "" {
+			orig += pluralSeparator + e.IDPlural
+		}
+		if e.Context != "" {
+			orig = e.Context + contextSeparator + orig
+		}
+		pairs = append(pairs, pair{orig, strings.Join(e.Str, pluralSeparator)})
+	}
+
+	// readers binary search the original strings
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].orig < pairs[j].orig })
+
+	count := uint32(len(pairs))
+	origTable := uint32(moHeaderSize)
+	transTable := origTable + count*8
+	offset := transTable + count*8
+
+	var header, tables, strs bytes.Buffer
+	le := binary.LittleEndian
+
+	for _, v := range []uint32{moMagic, 0, count, origTable, transTable, 0, offset} {
+		_ = binary.Write(&header, le, v)
+	}
+
+	origEntries := make([]uint32, 0, count*2)
+	transEntries := make([]uint32, 0, count*2)
+
+	add := func(s string) []uint32 {
+		entry := []uint32{uint32(len(s)), offset + uint32(strs.Len())}
+		strs.WriteString(s)
+		strs.WriteByte(0)
+		return entry
+	}
+
+	for _, p := range pairs {
+		origEntries = append(origEntries, add(p.orig)...)
+	}
+	for _, p := range pairs {
+		transEntries = append(transEntries, add(p.trans)...)
+	}
+
+	_ = binary.Write(&tables, le, origEntries)
+	_ = binary.Write(&tables, le, transEntries)
+
+	for _, b := range [][]byte{header.Bytes(), tables.Bytes(), strs.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
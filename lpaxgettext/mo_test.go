@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestMORoundTrip(t *testing.T) {
+	f, err := ReadPO(strings.NewReader(testPO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := WriteMO(&b, f); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := ReadMO(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Header.Get("Language") != "fr" {
+		t.Error("header", g.Header)
+	}
+
+	// fuzzy hello is not written, entries are sorted by original string
+	if len(g.Entries) != 2 {
+		t.Fatal("entries", len(g.Entries))
+	}
+
+	e := g.Entries[0]
+	if e.Context != "files" || e.ID != "%d file" || e.IDPlural != "%d files" || len(e.Str) != 2 || e.Str[1] != "%d fichiers" {
+		t.Error("files", e)
+	}
+
+	e = g.Entries[1]
+	if e.Context != "" || e.ID != "multi\nline \"quoted\"" || e.Str[0] != "multi\nligne" {
+		t.Error("multi", e)
+	}
+}
+
+func TestReadMOBigEndian(t *testing.T) {
+	var b bytes.Buffer
+	be := binary.BigEndian
+	for _, v := range []uint32{moMagic, 0, 1, 28, 36, 0, 44, 1, 44, 1, 46} {
+		_ = binary.Write(&b, be, v)
+	}
+	b.WriteString("a\x00b\x00")
+
+	f, err := ReadMO(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.Entries) != 1 || f.Entries[0].ID != "a" || f.Entries[0].Str[0] != "b" {
+		t.Error("entries", f.Entries)
+	}
+}
+
+func TestReadMOErrors(t *testing.T) {
+	le := binary.LittleEndian
+	header := func(values ...uint32) []byte {
+		var b bytes.Buffer
+		for _, v := range values {
+			_ = binary.Write(&b, le, v)
+		}
+		return b.Bytes()
+	}
+
+	for name, data := range map[string][]byte{
+		"short":         []byte("mo"),
+		"magic":         header(1, 0, 0, 0, 0, 0, 0),
+		"table":         header(moMagic, 0, 1, 100, 100, 0, 0),
+		"string range":  header(moMagic, 0, 1, 28, 28, 0, 0, 10, 100),
+		"count":         header(moMagic, 0, 0xffffffff, 28, 28, 0, 0, 0, 0),
+		"wrapped table": header(moMagic, 0, 2, 0xfffffff8, 28, 0, 0, 0, 0),
+		"truncated":     header(moMagic, 0, 2, 28, 36, 0, 0, 0, 0)[:40],
+	} {
+		if _, err := ReadMO(bytes.NewReader(data)); err == nil {
+			t.Error("no error", name)
+		}
+	}
+}
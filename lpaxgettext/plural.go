@@ -0,0 +1,449 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nehemming/lpax"
+)
+
+// pluralSamples is the number of counts, from zero, sampled to relate the plural forms of a Plural-Forms
+// expression to CLDR plural categories.  The CLDR integer rules depend on the count below 100 and the
+// count modulo 100 above, so the samples cover every case.
+const pluralSamples = 1000
+
+// cldrOrder is the order of the CLDR categories used for the plural forms of a language.
+var cldrOrder = []lpax.PluralCategory{
+	lpax.PluralZero, lpax.PluralOne, lpax.PluralTwo, lpax.PluralFew, lpax.PluralMany, lpax.PluralOther,
+}
+
+// pluralForms returns the Plural-Forms header value of a language, derived from its CLDR plural rules,
+// and the category of each plural form.
+func pluralForms(langTag lpax.Tag) (string, []lpax.PluralCategory) {
+	// the category of counts below 100, and of counts of 100 and more by the count modulo 100
+	var small, large [100]lpax.PluralCategory
+	used := make(map[lpax.PluralCategory]bool)
+	for r := 0; r < 100; r++ {
+		small[r] = lpax.PluralCategoryOf(langTag, r)
+		large[r] = lpax.PluralCategoryOf(langTag, 100+r)
+		used[small[r]], used[large[r]] = true, true
+	}
+
+	var categories []lpax.PluralCategory
+	for _, c := range cldrOrder {
+		if used[c] {
+			categories = append(categories, c)
+		}
+	}
+
+	if len(categories) == 1 {
+		return "nplurals=1; plural=0;", categories
+	}
+
+	var b strings.Builder
+	for i, c := range categories[:len(categories)-1] {
+		var s, l []int
+		for r := 0; r < 100; r++ {
+			if small[r] == c {
+				s = append(s, r)
+			}
+			if large[r] == c {
+				l = append(l, r)
+			}
+		}
+
+		fmt.Fprintf(&b, "(%s) ? %d : ", countCondition(s, l), i)
+	}
+	b.WriteString(strconv.Itoa(len(categories) - 1))
+
+	return fmt.Sprintf("nplurals=%d; plural=(%s);", len(categories), b.String()), categories
+}
+
+// countCondition returns the C condition matching the counts below 100 in small and the counts of 100
+// and more whose value modulo 100 is in large.
+func countCondition(small, large []int) string {
+	switch {
+	case equalInts(small, large):
+		return moduloCondition(small)
+	case len(large) == 0:
+		return rangeCondition("n", small, -1)
+	default:
+		return fmt.Sprintf("(n < 100 && (%s)) || (n >= 100 && (%s))", rangeCondition("n", small, -1), moduloCondition(large))
+	}
+}
+
+// moduloCondition returns the shorter C condition matching counts whose value modulo 100 is in values,
+// either using ranges of n % 100 or using ranges of n % 10 with exceptions.
+func moduloCondition(values []int) string {
+	in := make(map[int]bool, len(values))
+	perDigit := make(map[int]int)
+	for _, v := range values {
+		in[v] = true
+		perDigit[v%10]++
+	}
+
+	var digits, excluded, extra []int
+	for d := 0; d < 10; d++ {
+		if perDigit[d] > 5 {
+			digits = append(digits, d)
+		}
+	}
+	for r := 0; r < 100; r++ {
+		matched := perDigit[r%10] > 5
+		switch {
+		case matched && !in[r]:
+			excluded = append(excluded, r)
+		case !matched && in[r]:
+			extra = append(extra, r)
+		}
+	}
+
+	modulo := rangeCondition("n % 100", values, 99)
+	if len(digits) == 0 {
+		return modulo
+	}
+
+	c := "(" + rangeCondition("n % 10", digits, 9) + ")"
+	if len(excluded) > 0 {
+		c += " && !(" + rangeCondition("n % 100", excluded, 99) + ")"
+	}
+	if len(extra) > 0 {
+		c = "(" + c + ") || " + rangeCondition("n % 100", extra, 99)
+	}
+
+	if len(c) < len(modulo) {
+		return c
+	}
+	return modulo
+}
+
+// rangeCondition returns the C condition matching the ascending values of v, max is the largest value
+// v may have or -1 if unbounded.
+func rangeCondition(v string, values []int, max int) string {
+	var terms []string
+
+	for i := 0; i < len(values); {
+		j := i
+		for j+1 < len(values) && values[j+1] == values[j]+1 {
+			j++
+		}
+
+		lo, hi := values[i], values[j]
+		switch {
+		case lo == hi:
+			terms = append(terms, fmt.Sprintf("%s == %d", v, lo))
+		case lo == 0:
+			terms = append(terms, fmt.Sprintf("%s <= %d", v, hi))
+		case hi == max:
+			terms = append(terms, fmt.Sprintf("%s >= %d", v, lo))
+		default:
+			terms = append(terms, fmt.Sprintf("%s >= %d && %s <= %d", v, lo, v, hi))
+		}
+
+		i = j + 1
+	}
+
+	if len(terms) == 0 {
+		return "0"
+	}
+	return strings.Join(terms, " || ")
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pluralCategories returns the CLDR category of each plural form of a Plural-Forms header value in the
+// language langTag.  Each form must only be used for counts of one category, forms not used for any count
+// are the PluralOther category if no other form is.
+func pluralCategories(pluralForms string, langTag lpax.Tag) ([]lpax.PluralCategory, error) {
+	nplurals, expr, err := parsePluralForms(pluralForms)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]lpax.PluralCategory, nplurals)
+	mapped := make([]bool, nplurals)
+	other := false
+
+	for n := 0; n < pluralSamples; n++ {
+		form := expr.eval(n)
+		if form < 0 || form >= nplurals {
+			return nil, fmt.Errorf("plural form %d of %d is outside nplurals=%d", form, n, nplurals)
+		}
+
+		c := lpax.PluralCategoryOf(langTag, n)
+		if mapped[form] && categories[form] != c {
+			return nil, fmt.Errorf("plural form %d is used for the %s and %s categories of %s",
+				form, categories[form], c, langTag)
+		}
+
+		categories[form], mapped[form] = c, true
+		other = other || c == lpax.PluralOther
+	}
+
+	for form := range categories {
+		if mapped[form] {
+			continue
+		}
+		if other {
+			return nil, fmt.Errorf("plural form %d is not used for any %s count", form, langTag)
+		}
+		categories[form], other = lpax.PluralOther, true
+	}
+
+	return categories, nil
+}
+
+// parsePluralForms parses a Plural-Forms header value, e.g. nplurals=2; plural=(n != 1);
+func parsePluralForms(s string) (int, pluralExpr, error) {
+	nplurals, plural := -1, ""
+
+	for _, field := range strings.Split(s, ";") {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			continue
+		}
+
+		switch strings.TrimSpace(field[:i]) {
+		case "nplurals":
+			n, err := strconv.Atoi(strings.TrimSpace(field[i+1:]))
+			if err != nil || n < 1 {
+				return 0, nil, fmt.Errorf("invalid nplurals %q", field[i+1:])
+			}
+			nplurals = n
+		case "plural":
+			plural = field[i+1:]
+		}
+	}
+
+	if nplurals < 0 || plural == "" {
+		return 0, nil, fmt.Errorf("invalid Plural-Forms %q", s)
+	}
+
+	p := &exprParser{s: plural}
+	expr := p.ternary()
+	if p.skipSpace(); p.err == nil && p.i < len(p.s) {
+		p.err = fmt.Errorf("unexpected %q", p.s[p.i:])
+	}
+	if p.err != nil {
+		return 0, nil, fmt.Errorf("plural expression %q: %w", plural, p.err)
+	}
+
+	return nplurals, expr, nil
+}
+
+// pluralExpr is a parsed C expression of the count n.
+type pluralExpr interface {
+	eval(n int) int
+}
+
+type (
+	countExpr   struct{}
+	constExpr   int
+	notExpr     struct{ x pluralExpr }
+	ternaryExpr struct{ cond, then, otherwise pluralExpr }
+	binaryExpr  struct {
+		op   string
+		x, y pluralExpr
+	}
+)
+
+func (countExpr) eval(n int) int   { return n }
+func (e constExpr) eval(n int) int { return int(e) }
+func (e notExpr) eval(n int) int   { return boolInt(e.x.eval(n) == 0) }
+
+func (e ternaryExpr) eval(n int) int {
+	if e.cond.eval(n) != 0 {
+		return e.then.eval(n)
+	}
+	return e.otherwise.eval(n)
+}
+
+func (e binaryExpr) eval(n int) int {
+	x := e.x.eval(n)
+
+	// logical operators short circuit
+	switch e.op {
+	case "||":
+		return boolInt(x != 0 || e.y.eval(n) != 0)
+	case "&&":
+		return boolInt(x != 0 && e.y.eval(n) != 0)
+	}
+
+	y := e.y.eval(n)
+	switch e.op {
+	case "==":
+		return boolInt(x == y)
+	case "!=":
+		return boolInt(x != y)
+	case "<":
+		return boolInt(x < y)
+	case "<=":
+		return boolInt(x <= y)
+	case ">":
+		return boolInt(x > y)
+	case ">=":
+		return boolInt(x >= y)
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/", "%":
+		if y == 0 {
+			return 0
+		}
+		if e.op == "/" {
+			return x / y
+		}
+		return x % y
+	}
+
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// binaryLevels are the binary operators by increasing precedence, longer operators first.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// exprParser parses the C expression of a Plural-Forms plural field.
+type exprParser struct {
+	s   string
+	i   int
+	err error
+}
+
+func (p *exprParser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// accept consumes the token if it is next.
+func (p *exprParser) accept(token string) bool {
+	p.skipSpace()
+	if p.err != nil || !strings.HasPrefix(p.s[p.i:], token) {
+		return false
+	}
+
+	// "!" is not "!=" and "<" is not "<="
+	if len(token) == 1 && p.i+1 < len(p.s) && p.s[p.i+1] == '=' && strings.IndexByte("!<>=", token[0]) >= 0 {
+		return false
+	}
+
+	p.i += len(token)
+	return true
+}
+
+func (p *exprParser) expect(token string) {
+	if !p.accept(token) && p.err == nil {
+		p.err = fmt.Errorf("expected %q at offset %d", token, p.i)
+	}
+}
+
+func (p *exprParser) ternary() pluralExpr {
+	cond := p.binary(0)
+	if !p.accept("?") {
+		return cond
+	}
+
+	then := p.ternary()
+	p.expect(":")
+	otherwise := p.ternary()
+
+	return ternaryExpr{cond: cond, then: then, otherwise: otherwise}
+}
+
+func (p *exprParser) binary(level int) pluralExpr {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+
+	x := p.binary(level + 1)
+	for p.err == nil {
+		op := ""
+		for _, token := range binaryLevels[level] {
+			if p.accept(token) {
+				op = token
+				break
+			}
+		}
+		if op == "" {
+			return x
+		}
+
+		x = binaryExpr{op: op, x: x, y: p.binary(level + 1)}
+	}
+
+	return x
+}
+
+func (p *exprParser) unary() pluralExpr {
+	switch {
+	case p.accept("!"):
+		return notExpr{x: p.unary()}
+	case p.accept("("):
+		x := p.ternary()
+		p.expect(")")
+		return x
+	case p.accept("n"):
+		return countExpr{}
+	}
+
+	p.skipSpace()
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+	}
+
+	n, err := strconv.Atoi(p.s[start:p.i])
+	if err != nil {
+		if p.err == nil {
+			p.err = errors.New("expected a number, n or (")
+		}
+		return constExpr(0)
+	}
+
+	return constExpr(n)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+func TestPluralFormsMatchCLDR(t *testing.T) {
+	for _, lang := range []string{"en", "fr", "pl", "ru", "ar", "ja", "cy", "cs", "lt", "lv", "he", "ro", "mt", "br", "gd"} {
+		langTag := language.MustParse(lang)
+		forms, categories := pluralForms(langTag)
+
+		nplurals, expr, err := parsePluralForms(forms)
+		if err != nil || nplurals != len(categories) {
+			t.Error(lang, forms, err)
+			continue
+		}
+
+		for _, n := range []int{0, 1, 2, 3, 5, 11, 12, 21, 22, 25, 100, 101, 102, 111, 1000, 1001, 10000002, 10000012} {
+			if c := categories[expr.eval(n)]; c != lpax.PluralCategoryOf(langTag, n) {
+				t.Error(lang, n, c, forms)
+			}
+		}
+	}
+
+	if forms, _ := pluralForms(language.Polish); forms != "nplurals=3; plural=((n == 1) ? 0 : "+
+		"((n % 10 >= 2 && n % 10 <= 4) && !(n % 100 >= 12 && n % 100 <= 14)) ? 1 : 2);" {
+		t.Error("pl", forms)
+	}
+}
+
+func TestPluralCategories(t *testing.T) {
+	one, few, many, other := lpax.PluralOne, lpax.PluralFew, lpax.PluralMany, lpax.PluralOther
+
+	for _, tc := range []struct {
+		lang, forms string
+		categories  []lpax.PluralCategory
+	}{
+		{"en", "nplurals=2; plural=(n != 1);", []lpax.PluralCategory{one, other}},
+		{"fr", "nplurals=2; plural=(n > 1);", []lpax.PluralCategory{one, other}},
+		{"ja", "nplurals=1; plural=0;", []lpax.PluralCategory{other}},
+		{"pl", "nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);", []lpax.PluralCategory{one, few, many}},
+		{"ru", "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);", []lpax.PluralCategory{one, few, many}},
+		{"pl", "nplurals=4; plural=(n==1 ? 0 : (n%10>=2 && n%10<=4) && (n%100<12 || n%100>14) ? 1 : n!=1 && (n%10>=0 && n%10<=1) || (n%10>=5 && n%10<=9) || (n%100>=12 && n%100<=14) ? 2 : 3);",
+			[]lpax.PluralCategory{one, few, many, other}},
+	} {
+		categories, err := pluralCategories(tc.forms, language.MustParse(tc.lang))
+		if err != nil || !reflect.DeepEqual(categories, tc.categories) {
+			t.Error(tc.lang, tc.forms, categories, err)
+		}
+	}
+}
+
+func TestPluralCategoriesErrors(t *testing.T) {
+	for _, forms := range []string{
+		"nplurals=1; plural=0;",
+		"nplurals=2; plural=(n > 1);",
+		"nplurals=2; plural=(n != 1 ? 2 : 0);",
+		"nplurals=3; plural=(n != 1);",
+		"nplurals=x; plural=0;",
+		"plural=0;",
+		"nplurals=2; plural=(n != 1;",
+		"nplurals=2; plural=(n ! 1);",
+		"nplurals=2; plural=(n != 1) x;",
+	} {
+		if _, err := pluralCategories(forms, language.English); err == nil {
+			t.Error("no error", forms)
+		}
+	}
+}
+
+func TestTextMapPluralForms(t *testing.T) {
+	source := lpax.TextMap{testTextID(2): "%d file", testTextID(-2): "%d files"}
+	polish := lpax.TextMap{
+		testTextID(2):  "%d plik",
+		testTextID(-2): "%d plików",
+		lpax.ByCategory(testTextID(2), lpax.PluralFew): "%d pliki",
+	}
+
+	f := NewFile(language.Polish, source, polish, nil)
+	if forms := f.Header.Get("Plural-Forms"); forms[:11] != "nplurals=3;" {
+		t.Error("header", forms)
+	}
+
+	if e := f.Entries[0]; !reflect.DeepEqual(e.Str, []string{"%d plik", "%d pliki", "%d plików"}) {
+		t.Error("msgstr", e.Str)
+	}
+
+	var b bytes.Buffer
+	if err := WriteMO(&b, f); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := ReadMO(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm, err := g.TextMap(decodeTestTextID)
+	if err != nil || !reflect.DeepEqual(tm, polish) {
+		t.Error("round trip", tm, err)
+	}
+
+	for count, want := range map[int]string{1: "%d plik", 3: "%d pliki", 5: "%d plików", 22: "%d pliki"} {
+		if s, _ := lpax.FindCount(lpax.NewLanguageFinder(language.Polish, tm), testTextID(2), count); s != want {
+			t.Error(count, s)
+		}
+	}
+}
+
+func TestTextMapUnmappedForms(t *testing.T) {
+	f := &File{Entries: []*Entry{{Context: "2", ID: "%d file", IDPlural: "%d files", Str: []string{"a", "b", "c"}}}}
+	if _, err := f.TextMap(decodeTestTextID); err == nil {
+		t.Error("no Plural-Forms")
+	}
+
+	f.Header.Set("Language", "en")
+	f.Header.Set("Plural-Forms", "nplurals=1; plural=0;")
+	if _, err := f.TextMap(decodeTestTextID); err == nil {
+		t.Error("inconsistent Plural-Forms")
+	}
+
+	f.Header.Set("Language", "")
+	f.Header.Set("Plural-Forms", "nplurals=2; plural=(n != 1);")
+	if _, err := f.TextMap(decodeTestTextID); err == nil {
+		t.Error("no language")
+	}
+}
@@ -0,0 +1,429 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxgettext reads and writes GNU gettext .po and .mo files, mapping their
+// entries to and from lpax TextMaps.
+//
+// Each lpax text is exchanged as an entry whose msgctxt is the text's encoded TextID,
+// msgid and msgid_plural are the source language's Single and Plural texts and
+// msgstr[n] are the translated plural forms.  Files are written with a Plural-Forms header
+// derived from the CLDR plural rules of their language, and the plural forms of files read
+// are mapped to CLDR plural categories by evaluating their Plural-Forms expression.  The
+// one form is the Single text, the other form the Plural text and the forms of the other
+// categories, such as few and many, are lpax.ByCategory variants.
+package lpaxgettext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+type (
+	// HeaderField is a field of the file header, e.g. Language: fr.
+	HeaderField struct {
+		Name  string
+		Value string
+	}
+
+	// Header is the ordered list of fields in the file header entry.
+	Header []HeaderField
+
+	// Entry is a gettext message entry.
+	Entry struct {
+		// TranslatorComments are the "# " comments.
+		TranslatorComments []string
+
+		// ExtractedComments are the "#." comments.
+		ExtractedComments []string
+
+		// References are the "#:" source references.
+		References []string
+
+		// Flags are the "#," flags such as fuzzy or c-format.
+		Flags []string
+
+		// Context is the msgctxt.
+		Context string
+
+		// ID is the msgid.
+		ID string
+
+		// IDPlural is the msgid_plural.
+		IDPlural string
+
+		// Str holds the msgstr, or for plural entries each msgstr[n].
+		Str []string
+	}
+
+	// File is a gettext catalog.
+	File struct {
+		Header  Header
+		Entries []*Entry
+	}
+)
+
+// fuzzyFlag marks entries needing review.
+const fuzzyFlag = "fuzzy"
+
+// Get returns the value of the named field or an empty string.
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set sets the value of the named field, adding the field if not present.
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, HeaderField{Name: name, Value: value})
+}
+
+// String returns the header as the msgstr of the header entry.
+func (h Header) String() string {
+	var b strings.Builder
+	for _, f := range h {
+		b.WriteString(f.Name + ": " + f.Value + "\n")
+	}
+	return b.String()
+}
+
+// parseHeader parses the msgstr of a header entry.
+func parseHeader(s string) Header {
+	var h Header
+	for _, line := range strings.Split(s, "\n") {
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			h = append(h, HeaderField{Name: strings.TrimSpace(line[:i]), Value: strings.TrimSpace(line[i+1:])})
+		}
+	}
+	return h
+}
+
+// Language returns the language of the file's Language header field.
+func (f *File) Language() (language.Tag, error) {
+	return language.Parse(f.Header.Get("Language"))
+}
+
+// Fuzzy is true if the entry is flagged as fuzzy, needing review.
+func (e *Entry) Fuzzy() bool {
+	for _, flag := range e.Flags {
+		if flag == fuzzyFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFuzzy adds or removes the fuzzy flag.
+func (e *Entry) SetFuzzy(fuzzy bool) {
+	flags := make([]string, 0, len(e.Flags)+1)
+	for _, flag := range e.Flags {
+		if flag != fuzzyFlag {
+			flags = append(flags, flag)
+		}
+	}
+	if fuzzy {
+		flags = append(flags, fuzzyFlag)
+	}
+	e.Flags = flags
+}
+
+// poReader holds the state of a .po file being read.
+type poReader struct {
+	file    *File
+	entry   *Entry
+	target  *string
+	hasStr  bool
+	line    int
+	isFirst bool
+}
+
+// ReadPO reads a .po file.  Obsolete #~ entries are ignored.
+func ReadPO(r io.Reader) (*File, error) {
+	p := &poReader{file: &File{}, isFirst: true}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		p.line++
+		if err := p.parseLine(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p.finish()
+	return p.file, nil
+}
+
+// current returns the entry being read, starting a new entry if the last is complete.
+func (p *poReader) current() *Entry {
+	if p.entry != nil && p.hasStr {
+		p.finish()
+	}
+	if p.entry == nil {
+		p.entry = &Entry{}
+	}
+	return p.entry
+}
+
+// finish completes the entry being read.
+func (p *poReader) finish() {
+	e := p.entry
+	p.entry, p.target, p.hasStr = nil, nil, false
+
+	if e == nil {
+		return
+	}
+
+	if p.isFirst && e.ID == "" && e.Context == "" {
+		p.file.Header = parseHeader(strings.Join(e.Str, ""))
+	} else {
+		p.file.Entries = append(p.file.Entries, e)
+	}
+	p.isFirst = false
+}
+
+func (p *poReader) parseLine(line string) error {
+	switch {
+	case line == "":
+		p.target = nil
+		return nil
+	case strings.HasPrefix(line, "#~"), strings.HasPrefix(line, "#|"):
+		return nil // obsolete and previous entries are not retained
+	case strings.HasPrefix(line, "#"):
+		p.parseComment(line)
+		return nil
+	case strings.HasPrefix(line, `"`):
+		return p.parseContinuation(line)
+	}
+
+	keyword, value := line, ""
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		keyword, value = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	s, err := unquote(value)
+	if err != nil {
+		return err
+	}
+
+	return p.parseKeyword(keyword, s)
+}
+
+func (p *poReader) parseComment(line string) {
+	e := p.current()
+	p.target = nil
+
+	text := func(prefix string) string {
+		return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	}
+
+	switch {
+	case strings.HasPrefix(line, "#."):
+		e.ExtractedComments = append(e.ExtractedComments, text("#."))
+	case strings.HasPrefix(line, "#:"):
+		e.References = append(e.References, strings.Fields(text("#:"))...)
+	case strings.HasPrefix(line, "#,"):
+		for _, flag := range strings.Split(text("#,"), ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				e.Flags = append(e.Flags, flag)
+			}
+		}
+	default:
+		e.TranslatorComments = append(e.TranslatorComments, strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+	}
+}
+
+func (p *poReader) parseContinuation(line string) error {
+	if p.target == nil {
+		return fmt.Errorf("unexpected string %s", line)
+	}
+
+	s, err := unquote(line)
+	if err != nil {
+		return err
+	}
+
+	*p.target += s
+	return nil
+}
+
+func (p *poReader) parseKeyword(keyword, s string) error {
+	switch {
+	case keyword == "msgctxt":
+		e := p.current()
+		e.Context = s
+		p.target = &e.Context
+	case keyword == "msgid":
+		e := p.current()
+		e.ID = s
+		p.target = &e.ID
+	case keyword == "msgid_plural" && p.entry != nil:
+		p.entry.IDPlural = s
+		p.target = &p.entry.IDPlural
+	case keyword == "msgstr" && p.entry != nil:
+		p.entry.Str = []string{s}
+		p.target = &p.entry.Str[0]
+		p.hasStr = true
+	case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]") && p.entry != nil:
+		n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid keyword %s", keyword)
+		}
+		for len(p.entry.Str) <= n {
+			p.entry.Str = append(p.entry.Str, "")
+		}
+		p.entry.Str[n] = s
+		p.target = &p.entry.Str[n]
+		p.hasStr = true
+	default:
+		return fmt.Errorf("unexpected keyword %s", keyword)
+	}
+
+	return nil
+}
+
+// unquote unquotes a C style po string.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+
+	var b strings.Builder
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(body) {
+			return "", fmt.Errorf("invalid escape in %s", s)
+		}
+
+		switch body[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// quote quotes a string as a po string.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WritePO writes the file in .po format.
+func WritePO(w io.Writer, f *File) error {
+	bw := bufio.NewWriter(w)
+
+	if len(f.Header) > 0 {
+		writeString(bw, "msgid", "")
+		writeString(bw, "msgstr", f.Header.String())
+	}
+
+	for _, e := range f.Entries {
+		bw.WriteString("\n")
+		writeEntry(bw, e)
+	}
+
+	return bw.Flush()
+}
+
+func writeEntry(w *bufio.Writer, e *Entry) {
+	for _, c := range e.TranslatorComments {
+		w.WriteString(strings.TrimRight("# "+c, " ") + "\n")
+	}
+	for _, c := range e.ExtractedComments {
+		w.WriteString("#. " + c + "\n")
+	}
+	if len(e.References) > 0 {
+		w.WriteString("#: " + strings.Join(e.References, " ") + "\n")
+	}
+	if len(e.Flags) > 0 {
+		w.WriteString("#, " + strings.Join(e.Flags, ", ") + "\n")
+	}
+
+	if e.Context != "" {
+		writeString(w, "msgctxt", e.Context)
+	}
+	writeString(w, "msgid", e.ID)
+
+	if e.IDPlural == "" {
+		str := ""
+		if len(e.Str) > 0 {
+			str = e.Str[0]
+		}
+		writeString(w, "msgstr", str)
+		return
+	}
+
+	writeString(w, "msgid_plural", e.IDPlural)
+
+	strs := e.Str
+	if len(strs) == 0 {
+		strs = []string{"", ""}
+	}
+	for i, s := range strs {
+		writeString(w, fmt.Sprintf("msgstr[%d]", i), s)
+	}
+}
+
+// writeString writes a keyword and its string, splitting multi-line strings after each new line.
+func writeString(w *bufio.Writer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+
+	if len(lines) <= 1 {
+		w.WriteString(keyword + " " + quote(s) + "\n")
+		return
+	}
+
+	w.WriteString(keyword + ` ""` + "\n")
+	for _, line := range lines {
+		w.WriteString(quote(line) + "\n")
+	}
+}
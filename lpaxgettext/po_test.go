@@ -0,0 +1,147 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testPO = `# French translation
+msgid ""
+msgstr ""
+"Language: fr\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+
+# Translator comment
+#. Extracted comment
+#: main.go:10 main.go:20
+#, fuzzy, c-format
+msgctxt "hello"
+msgid "Hello World"
+msgstr "Bonjour le monde"
+
+msgctxt "files"
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] "%d fichiers"
+
+#| msgid "Old"
+msgid ""
+"multi\n"
+"line \"quoted\""
+msgstr "multi\nligne"
+
+#~ msgid "obsolete"
+#~ msgstr "obsolète"
+`
+
+func TestReadPO(t *testing.T) {
+	f, err := ReadPO(strings.NewReader(testPO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag, err := f.Language(); err != nil || tag.String() != "fr" {
+		t.Error("language", tag, err)
+	}
+
+	if len(f.Entries) != 3 {
+		t.Fatal("entries", len(f.Entries))
+	}
+
+	e := f.Entries[0]
+	if e.Context != "hello" || e.ID != "Hello World" || e.Str[0] != "Bonjour le monde" || !e.Fuzzy() {
+		t.Error("hello", e)
+	}
+
+	if len(e.TranslatorComments) != 1 || e.TranslatorComments[0] != "Translator comment" ||
+		len(e.ExtractedComments) != 1 || len(e.References) != 2 || len(e.Flags) != 2 {
+		t.Error("comments", e)
+	}
+
+	e = f.Entries[1]
+	if e.IDPlural != "%d files" || len(e.Str) != 2 || e.Str[1] != "%d fichiers" || e.Fuzzy() {
+		t.Error("files", e)
+	}
+
+	e = f.Entries[2]
+	if e.ID != "multi\nline \"quoted\"" || e.Str[0] != "multi\nligne" {
+		t.Error("multi", e)
+	}
+}
+
+func TestReadPOErrors(t *testing.T) {
+	for _, po := range []string{
+		`msgid "unterminated`,
+		`"orphan"`,
+		`msgid "a"` + "\n" + `msgstr[x] "b"`,
+		`unknown "a"`,
+		`msgid "a\"`,
+	} {
+		if _, err := ReadPO(strings.NewReader(po)); err == nil {
+			t.Error("no error", po)
+		}
+	}
+}
+
+func TestWritePORoundTrip(t *testing.T) {
+	f, err := ReadPO(strings.NewReader(testPO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Entries[0].SetFuzzy(false)
+
+	var b bytes.Buffer
+	if err := WritePO(&b, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), "msgid \"\"\n\"multi\\n\"\n\"line \\\"quoted\\\"\"\n") {
+		t.Error("multi line", b.String())
+	}
+
+	g, err := ReadPO(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Header.Get("language") != "fr" || len(g.Entries) != 3 {
+		t.Fatal("round trip", g)
+	}
+
+	if e := g.Entries[0]; e.Fuzzy() || len(e.Flags) != 1 || e.References[1] != "main.go:20" {
+		t.Error("flags", e)
+	}
+
+	if e := g.Entries[1]; e.Str[0] != "%d fichier" || e.Str[1] != "%d fichiers" {
+		t.Error("plural", e)
+	}
+}
+
+func TestHeaderSet(t *testing.T) {
+	var h Header
+	h.Set("Language", "fr")
+	h.Set("language", "de")
+
+	if len(h) != 1 || h.Get("LANGUAGE") != "de" || h.String() != "Language: de\n" {
+		t.Error("header", h)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+// TextIDEncoder encodes a TextID as the msgctxt of an entry.
+// The encoding must be reversible by the TextIDDecoder used to read the translations back.
+type TextIDEncoder func(id lpax.TextID) string
+
+// encodeString is the default encoder using the id's String version.
+func encodeString(id lpax.TextID) string {
+	return id.String()
+}
+
+// NewFile creates a catalog for translating the source texts into the language langTag.
+// target holds existing translations and may be nil, creating a template for a .pot file
+// if langTag is language.Und.  If encode is nil the String version of each id is used.
+// The Plural-Forms header and the msgstr of plural entries follow the CLDR plural categories of
+// langTag, a template has the placeholder Plural-Forms header written by xgettext.
+// Entries are ordered by their encoded id.
+func NewFile(langTag lpax.Tag, source, target lpax.TextMap, encode TextIDEncoder) *File {
+	if encode == nil {
+		encode = encodeString
+	}
+
+	f := &File{}
+	if langTag != language.Und {
+		f.Header.Set("Language", langTag.String())
+	}
+	f.Header.Set("MIME-Version", "1.0")
+	f.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+	f.Header.Set("Content-Transfer-Encoding", "8bit")
+
+	categories := []lpax.PluralCategory{lpax.PluralOne, lpax.PluralOther}
+	if langTag != language.Und {
+		var forms string
+		forms, categories = pluralForms(langTag)
+		f.Header.Set("Plural-Forms", forms)
+	} else {
+		f.Header.Set("Plural-Forms", "nplurals=INTEGER; plural=EXPRESSION;")
+	}
+
+	for _, id := range singleIDs(source) {
+		f.Entries = append(f.Entries, newEntry(id, source, target, encode, categories))
+	}
+
+	sort.SliceStable(f.Entries, func(i, j int) bool { return f.Entries[i].Context < f.Entries[j].Context })
+
+	return f
+}
+
+// singleIDs returns the distinct Single ids of the single and plural texts in tm.
+// Plural category variants are not included.
+func singleIDs(tm lpax.TextMap) []lpax.TextID {
+	distinct := make(map[lpax.TextID]bool)
+	ids := make([]lpax.TextID, 0, len(tm))
+
+	for k := range tm {
+		single := k.Single()
+		if (k == single || k == k.Plural()) && !distinct[single] {
+			distinct[single] = true
+			ids = append(ids, single)
+		}
+	}
+
+	return ids
+}
+
+// newEntry creates the entry of id, a plural entry has a msgstr for each of the plural form categories.
+func newEntry(id lpax.TextID, source, target lpax.TextMap, encode TextIDEncoder, categories []lpax.PluralCategory) *Entry {
+	plural := id.Plural()
+
+	e := &Entry{Context: encode(id)}
+
+	single, hasSingle := source[id]
+	e.ID = single
+
+	if pluralText, ok := source[plural]; ok && plural != id {
+		e.IDPlural = pluralText
+		if !hasSingle {
+			e.ID = pluralText
+		}
+
+		e.Str = make([]string, len(categories))
+		for i, c := range categories {
+			e.Str[i] = categoryText(target, id, c)
+		}
+		return e
+	}
+
+	e.Str = []string{target[id]}
+	return e
+}
+
+// categoryText returns the text of a plural category, its variant or else the Single version for
+// PluralOne and the Plural version for the other categories.
+func categoryText(tm lpax.TextMap, id lpax.TextID, c lpax.PluralCategory) string {
+	if t, ok := tm[lpax.ByCategory(id, c)]; ok {
+		return t
+	}
+	if c == lpax.PluralOne {
+		return tm[id.Single()]
+	}
+	return tm[id.Plural()]
+}
+
+// TextMap returns the file's translations keyed by the TextIDs decoded from each entry's msgctxt,
+// or its msgid if it has no context.  The msgstr of plural entries are mapped to CLDR plural categories
+// using the Plural-Forms and Language headers.  The PluralOne form is the Single version, the PluralOther
+// form, or else the last form, is the Plural version and the forms of other categories are stored as
+// ByCategory variants.  Without a Plural-Forms header msgstr[0] is the Single and msgstr[1] the Plural
+// version.  An error is returned if the forms cannot be mapped.  Fuzzy and untranslated entries are skipped.
+func (f *File) TextMap(decode lpax.TextIDDecoder) (lpax.TextMap, error) {
+	tm := make(lpax.TextMap, len(f.Entries))
+
+	categories, err := f.pluralCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range f.Entries {
+		if e.Fuzzy() || len(e.Str) == 0 {
+			continue
+		}
+
+		key := e.Context
+		if key == "" {
+			key = e.ID
+		}
+
+		id, err := decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", key, err)
+		}
+
+		if e.IDPlural == "" {
+			if e.Str[0] != "" {
+				tm[id.Single()] = e.Str[0]
+			}
+			continue
+		}
+
+		if len(e.Str) > len(categories) {
+			return nil, fmt.Errorf("entry %q: msgstr[%d] has no plural form", key, len(categories))
+		}
+
+		for form, t := range e.Str {
+			if t != "" {
+				tm[formID(id, categories, form)] = t
+			}
+		}
+	}
+
+	return tm, nil
+}
+
+// pluralCategories returns the category of each plural form of the file, PluralOne and PluralOther if it
+// has no Plural-Forms header.
+func (f *File) pluralCategories() ([]lpax.PluralCategory, error) {
+	forms := f.Header.Get("Plural-Forms")
+	if forms == "" || strings.Contains(forms, "INTEGER") {
+		return []lpax.PluralCategory{lpax.PluralOne, lpax.PluralOther}, nil
+	}
+
+	langTag, err := f.Language()
+	if err != nil {
+		return nil, fmt.Errorf("mapping Plural-Forms: %w", err)
+	}
+
+	return pluralCategories(forms, langTag)
+}
+
+// formID returns the TextID of a plural form of id.
+func formID(id lpax.TextID, categories []lpax.PluralCategory, form int) lpax.TextID {
+	c := categories[form]
+
+	switch {
+	case c == lpax.PluralOne:
+		return id.Single()
+	case c == lpax.PluralOther:
+		return id.Plural()
+	case !hasCategory(categories, lpax.PluralOther) && form == len(categories)-1:
+		// the last form is the Plural version of languages without an other form, e.g. the many form
+		return id.Plural()
+	case !hasCategory(categories, lpax.PluralOne) && form == 0:
+		return id.Single()
+	}
+
+	return lpax.ByCategory(id, c)
+}
+
+func hasCategory(categories []lpax.PluralCategory, c lpax.PluralCategory) bool {
+	for _, category := range categories {
+		if category == c {
+			return true
+		}
+	}
+	return false
+}
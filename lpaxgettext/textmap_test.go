@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgettext
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type testTextID int
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(lpax.IntTypeSingle(int(id)))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID(lpax.IntTypePlural(int(id)))
+}
+
+func (id testTextID) String() string {
+	return strconv.Itoa(int(id))
+}
+
+func decodeTestTextID(key string) (lpax.TextID, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, errors.New("bad key")
+	}
+	return testTextID(id), nil
+}
+
+var english = lpax.TextMap{
+	testTextID(1):  "Hello World",
+	testTextID(2):  "%d file",
+	testTextID(-2): "%d files",
+	testTextID(-3): "%d items",
+	lpax.ByCategory(testTextID(2), lpax.PluralMany): "many files",
+}
+
+var french = lpax.TextMap{
+	testTextID(1):  "Bonjour le monde",
+	testTextID(-2): "%d fichiers",
+}
+
+func TestNewFile(t *testing.T) {
+	f := NewFile(language.French, english, french, nil)
+
+	if f.Header.Get("Language") != "fr" {
+		t.Error("language", f.Header)
+	}
+
+	if len(f.Entries) != 3 {
+		t.Fatal("entries", len(f.Entries))
+	}
+
+	e := f.Entries[1]
+	if e.Context != "2" || e.ID != "%d file" || e.IDPlural != "%d files" || e.Str[0] != "" || e.Str[1] != "%d fichiers" {
+		t.Error("files", e)
+	}
+
+	e = f.Entries[2]
+	if e.Context != "3" || e.ID != "%d items" || e.IDPlural != "%d items" {
+		t.Error("items", e)
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	f := NewFile(language.Und, english, nil, nil)
+
+	if f.Header.Get("Language") != "" {
+		t.Error("language", f.Header)
+	}
+
+	if e := f.Entries[0]; e.Str[0] != "" {
+		t.Error("translated", e)
+	}
+}
+
+func TestTextMapRoundTrip(t *testing.T) {
+	f := NewFile(language.French, english, french, nil)
+	f.Entries[2].Str = []string{"%d élément", "%d éléments"}
+	f.Entries[2].SetFuzzy(true)
+
+	var b bytes.Buffer
+	if err := WritePO(&b, f); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := ReadPO(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm, err := g.TextMap(decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tm) != len(french) {
+		t.Error("len", len(tm), tm)
+	}
+
+	for k, v := range french {
+		if tm[k] != v {
+			t.Error(k, tm[k])
+		}
+	}
+}
+
+func TestTextMapNoContextUsesID(t *testing.T) {
+	f := &File{Entries: []*Entry{{ID: "7", Str: []string{"sept"}}, {ID: "x"}}}
+
+	tm, err := f.TextMap(decodeTestTextID)
+	if err != nil || tm[testTextID(7)] != "sept" {
+		t.Error("msgid", tm, err)
+	}
+
+	f.Entries = append(f.Entries, &Entry{ID: "x", Str: []string{"x"}})
+	if _, err = f.TextMap(decodeTestTextID); err == nil {
+		t.Error("no error")
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/nehemming/lpax/lpaxgrpc
+
+go 1.25.0
+
+require (
+	github.com/nehemming/lpax v0.0.0-20261018043950-a265a5a2b494
+	golang.org/x/text v0.40.0
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	github.com/cloudfoundry-attic/jibber_jabber v0.0.0-20151120183258-bcc4c8345a21 // indirect
+	github.com/nxadm/tail v1.4.11 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxgrpc provides gRPC interceptors propagating the caller's language.
+//
+// Server interceptors bind a TextFinder for the language found in the incoming
+// metadata to the handler's context, allowing handlers to use lpax.CtxSprintf and friends.
+// Client interceptors forward the language of the TextFinder bound to the outgoing context.
+package lpaxgrpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AcceptLanguageKey is the metadata key forwarded by the client interceptors.
+	AcceptLanguageKey = "accept-language"
+
+	// GRPCAcceptLanguageKey is the metadata key used by grpc-gateway to forward the HTTP Accept-Language header.
+	GRPCAcceptLanguageKey = "grpcgateway-accept-language"
+)
+
+type (
+	// Option configures the Server.
+	Option func(s *Server)
+
+	// Server provides server interceptors binding a TextFinder for the incoming
+	// language to the handler's context.
+	Server struct {
+		registry  lpax.TextRegistry
+		supported []lpax.Tag
+		matcher   language.Matcher
+		keys      []string
+	}
+
+	// serverStream overrides the context of a wrapped server stream.
+	serverStream struct {
+		grpc.ServerStream
+		ctx context.Context
+	}
+)
+
+// WithSupported restricts the languages resolved to the passed tags.  The first tag is
+// used when none of the request's languages are supported.
+func WithSupported(langTags ...lpax.Tag) Option {
+	return func(s *Server) {
+		s.supported = append([]lpax.Tag(nil), langTags...)
+	}
+}
+
+// WithMetadataKeys sets the incoming metadata keys searched, in order, for the request language.
+// The default keys are AcceptLanguageKey and GRPCAcceptLanguageKey.
+func WithMetadataKeys(keys ...string) Option {
+	return func(s *Server) {
+		s.keys = make([]string, len(keys))
+		for i, key := range keys {
+			s.keys[i] = strings.ToLower(key)
+		}
+	}
+}
+
+// New creates a new Server creating finders from the passed registry.
+// If registry is nil the lpax Default registry is used.
+func New(registry lpax.TextRegistry, options ...Option) *Server {
+	if registry == nil {
+		registry = lpax.Default()
+	}
+
+	s := &Server{
+		registry: registry,
+		keys:     []string{AcceptLanguageKey, GRPCAcceptLanguageKey},
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if len(s.supported) > 0 {
+		s.matcher = language.NewMatcher(s.supported)
+	}
+
+	return s
+}
+
+// UnaryServerInterceptor returns a unary server interceptor using the Default registry and default options.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return New(nil).Unary()
+}
+
+// StreamServerInterceptor returns a stream server interceptor using the Default registry and default options.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return New(nil).Stream()
+}
+
+// Unary returns a unary server interceptor binding the incoming language's TextFinder to the handler's context.
+func (s *Server) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(s.bind(ctx), req)
+	}
+}
+
+// Stream returns a stream server interceptor binding the incoming language's TextFinder to the stream's context.
+func (s *Server) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: s.bind(ss.Context())})
+	}
+}
+
+// Context returns the language bound context.
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// bind binds the incoming language's finder to the context.
+func (s *Server) bind(ctx context.Context) context.Context {
+	langTags := s.Tags(ctx)
+
+	options := make([]interface{}, len(langTags))
+	for i, tag := range langTags {
+		options[i] = tag
+	}
+
+	return lpax.WithContext(ctx, lpax.NewFinder(s.registry, options...))
+}
+
+// Tags returns the incoming context's languages, in preference order, resolved against the supported languages.
+// An empty list is returned if the metadata has no valid language and no supported languages are configured.
+func (s *Server) Tags(ctx context.Context) []lpax.Tag {
+	langTags := s.incomingTags(ctx)
+
+	if s.matcher == nil {
+		return langTags
+	}
+
+	_, index, _ := s.matcher.Match(langTags...)
+	return []lpax.Tag{s.supported[index]}
+}
+
+// incomingTags returns the languages found in the first metadata key with a valid value.
+func (s *Server) incomingTags(ctx context.Context) []lpax.Tag {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	for _, key := range s.keys {
+		for _, value := range md.Get(key) {
+			// ParseAcceptLanguage orders the tags by quality
+			if langTags, _, err := language.ParseAcceptLanguage(value); err == nil && len(langTags) > 0 {
+				return langTags
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnaryClientInterceptor returns a unary client interceptor forwarding the language of the TextFinder
+// bound to the outgoing context, see lpax.LanguageFromContext.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor forwarding the language of the TextFinder
+// bound to the outgoing context, see lpax.LanguageFromContext.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// outgoing adds the bound finder's language to the outgoing metadata unless a language is already present.
+func outgoing(ctx context.Context) context.Context {
+	tag, ok := lpax.LanguageFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	if md, found := metadata.FromOutgoingContext(ctx); found && len(md.Get(AcceptLanguageKey)) > 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, AcceptLanguageKey, tag.String())
+}
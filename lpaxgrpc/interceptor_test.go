@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testTextID int
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(lpax.IntTypeSingle(int(id)))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID(lpax.IntTypePlural(int(id)))
+}
+
+func (id testTextID) String() string {
+	return lpax.ReflectCoderString(id.Single())
+}
+
+const (
+	testPackID = 1
+	hello      = testTextID(1)
+)
+
+func newTestRegistry() lpax.TextRegistry {
+	texts := map[lpax.Tag]lpax.TextMap{
+		language.English: {hello: "Hello"},
+		language.French:  {hello: "Bonjour"},
+		language.German:  {hello: "Hallo"},
+	}
+
+	return lpax.NewRegistry().Register(testPackID, func(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+		return texts[langTag]
+	}, lpax.DefaultPriority, language.English, language.French, language.German)
+}
+
+// healthServer replies to every call with an error containing the localized hello text.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return nil, status.Error(codes.NotFound, lpax.CtxSprintf(ctx, hello))
+}
+
+func (healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	return status.Error(codes.NotFound, lpax.CtxSprintf(stream.Context(), hello))
+}
+
+func newTestClient(t *testing.T, s *Server) grpc_health_v1.HealthClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.Unary()), grpc.StreamInterceptor(s.Stream()))
+	grpc_health_v1.RegisterHealthServer(srv, healthServer{})
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func checkText(t *testing.T, client grpc_health_v1.HealthClient, ctx context.Context) string {
+	t.Helper()
+
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return status.Convert(err).Message()
+}
+
+func watchText(t *testing.T, client grpc_health_v1.HealthClient, ctx context.Context) string {
+	t.Helper()
+
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stream.Recv()
+	return status.Convert(err).Message()
+}
+
+func TestClientForwardsContextLanguage(t *testing.T) {
+	registry := newTestRegistry()
+	client := newTestClient(t, New(registry))
+
+	ctx := lpax.WithContext(context.Background(), lpax.NewFinder(registry, language.French))
+
+	if s := checkText(t, client, ctx); s != "Bonjour" {
+		t.Error("unary", s)
+	}
+
+	if s := watchText(t, client, ctx); s != "Bonjour" {
+		t.Error("stream", s)
+	}
+}
+
+func TestNoLanguageUsesDefault(t *testing.T) {
+	client := newTestClient(t, New(newTestRegistry()))
+
+	if s := checkText(t, client, context.Background()); s != "Hello" {
+		t.Error("unary", s)
+	}
+}
+
+func TestMetadataQuality(t *testing.T) {
+	registry := newTestRegistry()
+	client := newTestClient(t, New(registry))
+
+	// explicit metadata is not replaced by the bound finder's language
+	ctx := lpax.WithContext(context.Background(), lpax.NewFinder(registry, language.French))
+	ctx = metadata.AppendToOutgoingContext(ctx, AcceptLanguageKey, "fr;q=0.5, de-AT")
+
+	if s := checkText(t, client, ctx); s != "Hallo" {
+		t.Error("unary", s)
+	}
+
+	if s := watchText(t, client, ctx); s != "Hallo" {
+		t.Error("stream", s)
+	}
+}
+
+func TestMetadataKeysAndSupported(t *testing.T) {
+	client := newTestClient(t, New(newTestRegistry(),
+		WithMetadataKeys("X-Lang"),
+		WithSupported(language.German, language.English)))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-lang", "en-GB")
+	if s := checkText(t, client, ctx); s != "Hello" {
+		t.Error("x-lang", s)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-lang", "fr")
+	if s := checkText(t, client, ctx); s != "Hallo" {
+		t.Error("unsupported", s)
+	}
+}
+
+func TestDefaultInterceptors(t *testing.T) {
+	lpax.Default().Register(testPackID, func(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+		return lpax.TextMap{hello: "Hola"}
+	}, lpax.DefaultPriority, language.Spanish)
+
+	md := metadata.Pairs(GRPCAcceptLanguageKey, "es")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	_, _ = UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if s := lpax.CtxSprintf(ctx, hello); s != "Hola" {
+			t.Error("unary", s)
+		}
+		return nil, nil
+	})
+
+	if StreamServerInterceptor() == nil {
+		t.Error("stream nil")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxhttp provides net/http middleware binding a language specific lpax.TextFinder
+// to each request's context, allowing handlers to use lpax.CtxSprintf and friends.
+//
+// The request language is taken, in order of preference, from the lang query parameter,
+// the lang cookie and the Accept-Language header.  Passing the en-XA or ar-XB pseudo-locale,
+// e.g. ?lang=en-XA, renders the pseudo-localized default language text.
+package lpaxhttp
+
+import (
+	"net/http"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+// DefaultParam is the default name of the query parameter and cookie overriding the Accept-Language header.
+const DefaultParam = "lang"
+
+type (
+	// Option configures the Middleware.
+	Option func(m *Middleware)
+
+	// Middleware binds a TextFinder resolved for the request's language to the request's context.
+	// Finders are cached per resolved language by the registry.
+	Middleware struct {
+		registry   lpax.TextRegistry
+		supported  []lpax.Tag
+		matcher    language.Matcher
+		queryParam string
+		cookieName string
+	}
+)
+
+// WithSupported restricts the languages resolved to the passed tags.  The first tag is
+// used when none of the request's languages are supported.  A request preferring the en-XA or ar-XB
+// pseudo-locale uses it whether or not it is supported.
+func WithSupported(langTags ...lpax.Tag) Option {
+	return func(m *Middleware) {
+		m.supported = append([]lpax.Tag(nil), langTags...)
+	}
+}
+
+// WithQueryParam sets the name of the query parameter used to override the request language.
+// An empty name disables the query parameter.
+func WithQueryParam(name string) Option {
+	return func(m *Middleware) {
+		m.queryParam = name
+	}
+}
+
+// WithCookie sets the name of the cookie used to override the request language.
+// An empty name disables the cookie.
+func WithCookie(name string) Option {
+	return func(m *Middleware) {
+		m.cookieName = name
+	}
+}
+
+// New creates a new Middleware creating finders from the passed registry.
+// If registry is nil the lpax Default registry is used.
+func New(registry lpax.TextRegistry, options ...Option) *Middleware {
+	if registry == nil {
+		registry = lpax.Default()
+	}
+
+	m := &Middleware{
+		registry:   registry,
+		queryParam: DefaultParam,
+		cookieName: DefaultParam,
+	}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	if len(m.supported) > 0 {
+		m.matcher = language.NewMatcher(m.supported)
+	}
+
+	return m
+}
+
+// Handler returns a handler using the Default registry and default options.
+func Handler(next http.Handler) http.Handler {
+	return New(nil).Handler(next)
+}
+
+// Handler wraps next, binding the request language's TextFinder to the request context.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		if m.cookieName != "" {
+			w.Header().Add("Vary", "Cookie")
+		}
+
+		tf := m.Finder(r)
+
+		next.ServeHTTP(w, r.WithContext(lpax.WithContext(r.Context(), tf)))
+	})
+}
+
+// Finder returns the TextFinder for the request's language.
+func (m *Middleware) Finder(r *http.Request) lpax.TextFinder {
+	langTags := m.Tags(r)
+
+	options := make([]interface{}, len(langTags))
+	for i, tag := range langTags {
+		options[i] = tag
+	}
+
+	return lpax.NewFinder(m.registry, options...)
+}
+
+// Tags returns the request's languages, in preference order, resolved against the supported languages.
+// An empty list is returned if the request specifies no valid language and no supported languages are configured.
+// A preferred pseudo-locale is not resolved, as the nearest supported language would hide it.
+func (m *Middleware) Tags(r *http.Request) []lpax.Tag {
+	langTags := m.requestTags(r)
+
+	if m.matcher == nil {
+		return langTags
+	}
+
+	if len(langTags) > 0 && lpax.IsPseudoLocale(langTags[0]) {
+		return langTags[:1]
+	}
+
+	_, index, _ := m.matcher.Match(langTags...)
+	return []lpax.Tag{m.supported[index]}
+}
+
+// requestTags returns the languages requested by the query, cookie or Accept-Language header.
+func (m *Middleware) requestTags(r *http.Request) []lpax.Tag {
+	if m.queryParam != "" {
+		if tag, err := language.Parse(r.URL.Query().Get(m.queryParam)); err == nil {
+			return []lpax.Tag{tag}
+		}
+	}
+
+	if m.cookieName != "" {
+		if cookie, err := r.Cookie(m.cookieName); err == nil {
+			if tag, err := language.Parse(cookie.Value); err == nil {
+				return []lpax.Tag{tag}
+			}
+		}
+	}
+
+	// ParseAcceptLanguage orders the tags by quality
+	langTags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		return nil
+	}
+
+	return langTags
+}
@@ -0,0 +1,236 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type testTextID int
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(lpax.IntTypeSingle(int(id)))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID(lpax.IntTypePlural(int(id)))
+}
+
+func (id testTextID) String() string {
+	return lpax.ReflectCoderString(id.Single())
+}
+
+const (
+	testPackID = 1
+	hello      = testTextID(1)
+)
+
+func newTestRegistry() lpax.TextRegistry {
+	texts := map[lpax.Tag]lpax.TextMap{
+		language.English: {hello: "Hello"},
+		language.French:  {hello: "Bonjour"},
+		language.German:  {hello: "Hallo"},
+	}
+
+	return lpax.NewRegistry().Register(testPackID, func(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+		return texts[langTag]
+	}, lpax.DefaultPriority, language.English, language.French, language.German)
+}
+
+func serve(t *testing.T, h http.Handler, r *http.Request) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	return string(body)
+}
+
+var helloHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(lpax.CtxSprintf(r.Context(), hello)))
+})
+
+func TestAcceptLanguageQuality(t *testing.T) {
+	h := New(newTestRegistry()).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "de;q=0.5, fr-CH, en;q=0.8")
+
+	if s := serve(t, h, r); s != "Bonjour" {
+		t.Error("Mismatch", s)
+	}
+}
+
+func TestNoLanguageUsesDefault(t *testing.T) {
+	h := New(newTestRegistry()).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if s := serve(t, h, r); s != "Hello" {
+		t.Error("Mismatch", s)
+	}
+}
+
+func TestQueryOverride(t *testing.T) {
+	h := New(newTestRegistry()).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/?lang=de", nil)
+	r.Header.Set("Accept-Language", "fr")
+	r.AddCookie(&http.Cookie{Name: DefaultParam, Value: "en"})
+
+	if s := serve(t, h, r); s != "Hallo" {
+		t.Error("Mismatch", s)
+	}
+}
+
+func TestPseudoLocale(t *testing.T) {
+	h := New(newTestRegistry()).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/?lang=en-XA", nil)
+	if s := serve(t, h, r); s != "[Ĥéļļö one]" {
+		t.Error("Mismatch", s)
+	}
+
+	// pseudo-locales are not matched to the nearest supported language
+	h = New(newTestRegistry(), WithSupported(language.English, language.French)).Handler(helloHandler)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "en-XA, fr;q=0.5")
+	if s := serve(t, h, r); s != "[Ĥéļļö one]" {
+		t.Error("Supported", s)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/?lang=ar-XB", nil)
+	if s := serve(t, h, r); !strings.Contains(s, "Hello") || s == "Hello" {
+		t.Error("Supported bidi", s)
+	}
+
+	// a pseudo-locale that is not preferred is matched with the other languages
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr, en-XA;q=0.5")
+	if s := serve(t, h, r); s != "Bonjour" {
+		t.Error("Not preferred", s)
+	}
+}
+
+func TestCookieOverride(t *testing.T) {
+	h := New(newTestRegistry(), WithCookie("locale")).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/?lang=bad-lang-tag-x", nil)
+	r.Header.Set("Accept-Language", "fr")
+	r.AddCookie(&http.Cookie{Name: "locale", Value: "de"})
+
+	if s := serve(t, h, r); s != "Hallo" {
+		t.Error("Mismatch", s)
+	}
+}
+
+func TestDisabledOverrides(t *testing.T) {
+	h := New(newTestRegistry(), WithQueryParam(""), WithCookie("")).Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/?lang=de", nil)
+	r.Header.Set("Accept-Language", "fr")
+	r.AddCookie(&http.Cookie{Name: DefaultParam, Value: "de"})
+
+	if s := serve(t, h, r); s != "Bonjour" {
+		t.Error("Mismatch", s)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	m := New(newTestRegistry(), WithSupported(language.German, language.French))
+	h := m.Handler(helloHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "en, fr-CA;q=0.5")
+
+	if s := serve(t, h, r); s != "Bonjour" {
+		t.Error("Mismatch", s)
+	}
+
+	r.Header.Set("Accept-Language", "ja")
+	if s := serve(t, h, r); s != "Hallo" {
+		t.Error("Unsupported", s)
+	}
+
+	if tags := m.Tags(r); len(tags) != 1 || tags[0] != language.German {
+		t.Error("Tags", tags)
+	}
+}
+
+func TestFinderCached(t *testing.T) {
+	m := New(newTestRegistry())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+
+	if m.Finder(r) != m.Finder(r) {
+		t.Error("not cached")
+	}
+}
+
+func TestVary(t *testing.T) {
+	for _, test := range []struct {
+		options []Option
+		want    string
+	}{
+		{nil, "Accept-Language, Cookie"},
+		{[]Option{WithCookie("")}, "Accept-Language"},
+	} {
+		w := httptest.NewRecorder()
+		New(newTestRegistry(), test.options...).Handler(helloHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if vary := strings.Join(w.Header().Values("Vary"), ", "); vary != test.want {
+			t.Error("Vary", vary, test.want)
+		}
+	}
+}
+
+func TestFinderCachedForLongAcceptLanguage(t *testing.T) {
+	m := New(newTestRegistry())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr-CA,fr;q=0.9,en-US;q=0.8,en;q=0.7,de;q=0.6,it;q=0.5")
+
+	r2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	r2.Header.Set("Accept-Language", "fr-FR,fr;q=0.9,es;q=0.8,pt;q=0.7,nl;q=0.6")
+
+	if m.Finder(r) != m.Finder(r2) {
+		t.Error("not cached by resolved language")
+	}
+}
+
+func TestHandlerUsesDefault(t *testing.T) {
+	lpax.Default().Register(testPackID, func(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+		return lpax.TextMap{hello: "Hola"}
+	}, lpax.DefaultPriority, language.Spanish)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "es")
+
+	if s := serve(t, Handler(helloHandler), r); s != "Hola" {
+		t.Error("Mismatch", s)
+	}
+}
@@ -0,0 +1,405 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxmobile
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nehemming/lpax"
+)
+
+type (
+	androidString struct {
+		Name string `xml:"name,attr"`
+		Text string `xml:",innerxml"`
+	}
+
+	androidPlurals struct {
+		Name  string `xml:"name,attr"`
+		Items []struct {
+			Quantity string `xml:"quantity,attr"`
+			Text     string `xml:",innerxml"`
+		} `xml:"item"`
+	}
+)
+
+// ReadAndroid reads an Android strings.xml resource file, decoding each string name using decode.
+// The <string> and <plurals> resources are read, other resources such as <string-array> are ignored.
+// A comment immediately preceding a resource is read as its translator comment.
+func ReadAndroid(r io.Reader, decode lpax.TextIDDecoder) (*File, error) {
+	f := newFile()
+	d := xml.NewDecoder(r)
+	comment := ""
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return f, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.Comment:
+			comment = strings.TrimSpace(string(t))
+			continue
+		case xml.StartElement:
+			if err := f.readAndroidElement(d, t, decode, comment); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, ok := tok.(xml.CharData); !ok {
+			comment = ""
+		}
+	}
+}
+
+// readAndroidElement reads a resource element.
+func (f *File) readAndroidElement(d *xml.Decoder, start xml.StartElement, decode lpax.TextIDDecoder, comment string) error {
+	var (
+		name  string
+		texts = make(map[lpax.TextID]string)
+	)
+
+	switch start.Name.Local {
+	case "resources":
+		return nil
+
+	case "string":
+		var s androidString
+		if err := d.DecodeElement(&s, &start); err != nil {
+			return err
+		}
+
+		id, err := decode(s.Name)
+		if err != nil {
+			return fmt.Errorf("string %q: %w", s.Name, err)
+		}
+
+		name, texts[id.Single()] = s.Name, s.Text
+
+	case "plurals":
+		var p androidPlurals
+		if err := d.DecodeElement(&p, &start); err != nil {
+			return err
+		}
+
+		id, err := decode(p.Name)
+		if err != nil {
+			return fmt.Errorf("plurals %q: %w", p.Name, err)
+		}
+
+		for _, item := range p.Items {
+			key, err := quantityTextID(id, item.Quantity)
+			if err != nil {
+				return fmt.Errorf("plurals %q: %w", p.Name, err)
+			}
+			texts[key] = item.Text
+		}
+
+		name = p.Name
+
+	default:
+		return d.Skip()
+	}
+
+	if len(texts) == 0 {
+		return fmt.Errorf("%s %q has no text", start.Name.Local, name)
+	}
+
+	for id, text := range texts {
+		f.Texts[id] = convertVerbs(unescapeAndroid(text), javaDialect, goDialect)
+
+		if comment != "" {
+			f.Comments[id.Single()] = comment
+		}
+	}
+
+	return nil
+}
+
+// unescapeAndroid returns the text of the inner XML of a string resource.
+// Whitespace outside double quotes is collapsed and trimmed and backslash escapes are replaced.
+// Markup, such as <b> or <xliff:g>, is kept unchanged.
+func unescapeAndroid(s string) string {
+	var b strings.Builder
+	quoted, space := false, false
+	end := 0 // length excluding trailing unquoted white space
+
+	for len(s) > 0 {
+		// the decoder only accepts well formed inner XML, so each < starts markup
+		if s[0] == '<' {
+			n := markupEnd(s, 0)
+			if n < 0 {
+				n = len(s)
+				if i := strings.IndexByte(s, '>'); i >= 0 {
+					n = i + 1
+				}
+			}
+
+			b.WriteString(s[:n])
+			s = s[n:]
+			space, end = false, b.Len()
+			continue
+		}
+
+		n := strings.IndexByte(s, '<')
+		if n < 0 {
+			n = len(s)
+		}
+
+		text := html.UnescapeString(s[:n])
+		s = s[n:]
+
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+
+			switch {
+			case c == '"':
+				quoted = !quoted
+				continue
+
+			case !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+				if !space && b.Len() > 0 {
+					b.WriteByte(' ')
+				}
+				space = true
+				continue
+
+			case c == '\\' && i+1 < len(text):
+				i++
+				switch text[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(text[i])
+				}
+
+			default:
+				b.WriteByte(c)
+			}
+
+			space, end = false, b.Len()
+		}
+	}
+
+	return b.String()[:end]
+}
+
+// escapeAndroid returns the text escaped for use in a string resource.  Markup tags, such as <b> or
+// <xliff:g id="count">, are written unchanged if each opening tag is closed, any other < or > is escaped.
+func escapeAndroid(s string) string {
+	var b strings.Builder
+	tags := markupTags(s)
+
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+
+		switch c {
+		case '\\', '\'', '"':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case '@', '?':
+			if i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			if end, ok := tags[i]; ok {
+				b.WriteString(s[i:end])
+				size = end - i
+			} else {
+				b.WriteString("&lt;")
+			}
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteRune(c)
+		}
+
+		i += size
+	}
+
+	// preserve leading, trailing and repeated spaces
+	if s != strings.TrimSpace(s) || strings.Contains(s, "  ") {
+		return `"` + b.String() + `"`
+	}
+
+	return b.String()
+}
+
+// markupTags returns the end of each markup tag in s keyed by the offset of its <.  Self closing tags and
+// opening tags followed by their closing tag are markup, other tags are text.
+func markupTags(s string) map[int]int {
+	type open struct {
+		name       string
+		start, end int
+	}
+
+	tags := make(map[int]int)
+	var stack []open
+
+	for i := strings.IndexByte(s, '<'); i >= 0; {
+		end := markupEnd(s, i)
+		if end < 0 {
+			end = i + 1
+		} else {
+			name := strings.TrimPrefix(s[i+1:end-1], "/")
+			if n := strings.IndexAny(name, " \t\r\n/"); n >= 0 {
+				name = name[:n]
+			}
+
+			switch {
+			case s[i+1] == '/':
+				// an unclosed opening tag between the tag and its closing tag is text
+				for j := len(stack) - 1; j >= 0; j-- {
+					if stack[j].name == name {
+						tags[stack[j].start], tags[i] = stack[j].end, end
+						stack = stack[:j]
+						break
+					}
+				}
+			case s[end-2] == '/':
+				tags[i] = end
+			default:
+				stack = append(stack, open{name: name, start: i, end: end})
+			}
+		}
+
+		next := strings.IndexByte(s[end:], '<')
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+
+	return tags
+}
+
+// markupEnd returns the end of the opening, closing or self closing tag starting with the < at s[i], or -1
+// if it is not a tag.  Attribute values must be quoted.
+func markupEnd(s string, i int) int {
+	i++
+	if i < len(s) && s[i] == '/' {
+		i++
+	}
+
+	name := i
+	for i < len(s) && isNameByte(s[i], i == name) {
+		i++
+	}
+	if i == name {
+		return -1
+	}
+
+	for {
+		attr := i
+		for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+			i++
+		}
+
+		switch {
+		case i == len(s):
+			return -1
+		case s[i] == '>':
+			return i + 1
+		case strings.HasPrefix(s[i:], "/>"):
+			return i + 2
+		case i == attr:
+			// attributes are separated from the name and each other by white space
+			return -1
+		}
+
+		start := i
+		for i < len(s) && isNameByte(s[i], i == start) {
+			i++
+		}
+		if i == start || i+1 >= len(s) || s[i] != '=' || (s[i+1] != '"' && s[i+1] != '\'') {
+			return -1
+		}
+
+		quote := strings.IndexByte(s[i+2:], s[i+1])
+		if quote < 0 {
+			return -1
+		}
+		i += 2 + quote + 1
+	}
+}
+
+// isNameByte reports if c may be used in an XML name, first is true for the first byte of the name.
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		return true
+	case first:
+		return false
+	}
+	return c >= '0' && c <= '9' || c == '-' || c == '.'
+}
+
+// WriteAndroid writes the file as an Android strings.xml resource file ordered by name.
+// Texts with a Plural version or plural category variants are written as <plurals>.
+// If encode is nil the String version of each id is used.
+func WriteAndroid(w io.Writer, f *File, encode TextIDEncoder) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<resources>\n")
+
+	for _, e := range f.entries(encode) {
+		if comment, ok := f.Comments[e.id]; ok {
+			fmt.Fprintf(bw, "    <!-- %s -->\n", strings.ReplaceAll(comment, "--", "- -"))
+		}
+
+		name := html.EscapeString(e.name)
+
+		if e.quantities == nil {
+			fmt.Fprintf(bw, "    <string name=\"%s\">%s</string>\n", name, androidText(f.Texts[e.id]))
+			continue
+		}
+
+		fmt.Fprintf(bw, "    <plurals name=\"%s\">\n", name)
+		for _, q := range e.quantities {
+			fmt.Fprintf(bw, "        <item quantity=\"%s\">%s</item>\n", q.name, androidText(q.text))
+		}
+		bw.WriteString("    </plurals>\n")
+	}
+
+	bw.WriteString("</resources>\n")
+
+	return bw.Flush()
+}
+
+// androidText converts a text to a string resource.
+func androidText(text string) string {
+	return escapeAndroid(convertVerbs(text, goDialect, javaDialect))
+}
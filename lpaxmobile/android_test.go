@@ -0,0 +1,195 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxmobile
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nehemming/lpax"
+)
+
+type testTextID string
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID("-" + strings.TrimPrefix(string(id), "-"))
+}
+
+func (id testTextID) String() string {
+	return string(id)
+}
+
+func decodeTestTextID(key string) (lpax.TextID, error) {
+	if key == "" || strings.HasPrefix(key, "-") {
+		return nil, errors.New("bad key")
+	}
+	return testTextID(key), nil
+}
+
+const stringsXML = `<?xml version="1.0" encoding="utf-8"?>
+<!-- file header -->
+<resources>
+    <!-- Shown on launch -->
+    <string name="hello">Hello   %1$s, it\'s \"here\" &amp; now</string>
+    <string name="spaced">"  two  spaces "</string>
+    <string-array name="planets"><item>Mercury</item></string-array>
+    <string name="at">\@home\nnext %%</string>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="few">%d files (few)</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>
+`
+
+func TestReadAndroid(t *testing.T) {
+	f, err := ReadAndroid(strings.NewReader(stringsXML), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[lpax.TextID]string{
+		testTextID("hello"):  `Hello %[1]v, it's "here" & now`,
+		testTextID("spaced"): "  two  spaces ",
+		testTextID("at"):     "@home\nnext %%",
+		testTextID("files"):  "%d file",
+		testTextID("-files"): "%d files",
+		lpax.ByCategory(testTextID("files"), lpax.PluralFew): "%d files (few)",
+	} {
+		if got := f.Texts[id]; got != want {
+			t.Errorf("%s got %q want %q", id, got, want)
+		}
+	}
+
+	if len(f.Texts) != 6 {
+		t.Error("texts", f.Texts)
+	}
+
+	if len(f.Comments) != 1 || f.Comments[testTextID("hello")] != "Shown on launch" {
+		t.Error("comments", f.Comments)
+	}
+}
+
+func TestReadAndroidErrors(t *testing.T) {
+	for _, doc := range []string{
+		`<resources><string name="-bad">x</string></resources>`,
+		`<resources><plurals name="-bad"><item quantity="one">x</item></plurals></resources>`,
+		`<resources><plurals name="bad"><item quantity="lots">x</item></plurals></resources>`,
+		`<resources><plurals name="bad"></plurals></resources>`,
+		`<resources><string name="bad">x</resources>`,
+		`<resources><plurals name="bad"><item>x</plurals></resources>`,
+	} {
+		if _, err := ReadAndroid(strings.NewReader(doc), decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestAndroidStyledRoundTrip(t *testing.T) {
+	const styled = `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="inbox">Hello <b>%1$s</b>, <xliff:g id="count" example="5">%2$d</xliff:g> &lt;new&gt; messages<br/></string>
+</resources>
+`
+
+	f, err := ReadAndroid(strings.NewReader(styled), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Hello <b>%[1]v</b>, <xliff:g id="count" example="5">%[2]d</xliff:g> <new> messages<br/>`
+	if got := f.Texts[testTextID("inbox")]; got != want {
+		t.Errorf("read got %q want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAndroid(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != styled {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), styled)
+	}
+}
+
+func TestMarkupTags(t *testing.T) {
+	for s, want := range map[string]int{
+		"<b>bold</b>":               2,
+		"a < b > c":                 0,
+		"<b>unclosed":               0,
+		"<i><b>x</i>":               2,
+		`<a href='x'>link</a><br/>`: 3,
+		"<a href=x>bad</a>":         0,
+	} {
+		if got := len(markupTags(s)); got != want {
+			t.Error(s, got, want)
+		}
+	}
+}
+
+func TestWriteAndroid(t *testing.T) {
+	f := &File{
+		Texts: lpax.TextMap{
+			testTextID("hello"):  "Hello %[1]v, it's <b> & %q",
+			testTextID("spaced"): " lead",
+			testTextID("at"):     "@home\t100%%",
+			testTextID("files"):  "%d file",
+			testTextID("-files"): "%d files",
+			lpax.ByCategory(testTextID("files"), lpax.PluralMany):       "%d many",
+			lpax.ByOrdinalCategory(testTextID("place"), lpax.PluralOne): "not written",
+		},
+		Comments: map[lpax.TextID]string{testTextID("hello"): "greeting -- user"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAndroid(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="at">\@home\t100%%</string>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="many">%d many</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+    <!-- greeting - - user -->
+    <string name="hello">Hello %1$s, it\'s &lt;b&gt; &amp; %s</string>
+    <string name="spaced">" lead"</string>
+</resources>
+`
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	read, err := ReadAndroid(&buf, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Texts[testTextID("hello")] != "Hello %[1]v, it's <b> & %v" || read.Texts[testTextID("spaced")] != " lead" ||
+		read.Texts[testTextID("at")] != "@home\t100%%" || len(read.Texts) != 6 {
+		t.Error("round trip", read.Texts)
+	}
+}
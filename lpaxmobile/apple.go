@@ -0,0 +1,454 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxmobile
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	formatKey     = "NSStringLocalizedFormatKey"
+	specTypeKey   = "NSStringFormatSpecTypeKey"
+	valueTypeKey  = "NSStringFormatValueTypeKey"
+	pluralRuleKey = "NSStringPluralRuleType"
+
+	// pluralVariable is the variable name used for the plural rule of written entries.
+	pluralVariable = "value"
+)
+
+// stringsdictVariable matches a %#@variable@ reference in a localized format.
+var stringsdictVariable = regexp.MustCompile(`%(?:\d+\$)?#@([^@]*)@`)
+
+// ReadStrings reads an Apple .strings file encoded as UTF-8 or as UTF-16 with a byte order mark,
+// decoding each key using decode.  A comment immediately preceding an entry is read as its
+// translator comment.
+func ReadStrings(r io.Reader, decode lpax.TextIDDecoder) (*File, error) {
+	b, err := ioutil.ReadAll(transform.NewReader(r, unicode.BOMOverride(unicode.UTF8.NewDecoder())))
+	if err != nil {
+		return nil, err
+	}
+
+	f := newFile()
+	s := &stringsScanner{src: string(b)}
+
+	for {
+		comment, err := s.skipSpace()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line(), err)
+		}
+		if s.pos == len(s.src) {
+			return f, nil
+		}
+
+		key, value, err := s.entry()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line(), err)
+		}
+
+		id, err := decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+
+		f.Texts[id.Single()] = convertVerbs(value, appleDialect, goDialect)
+		if comment != "" {
+			f.Comments[id.Single()] = comment
+		}
+	}
+}
+
+// stringsScanner scans the entries of a .strings file.
+type stringsScanner struct {
+	src string
+	pos int
+}
+
+// line returns the current line number.
+func (s *stringsScanner) line() int {
+	return strings.Count(s.src[:s.pos], "\n") + 1
+}
+
+// skipSpace skips white space and comments, returning the last comment skipped.
+func (s *stringsScanner) skipSpace() (comment string, err error) {
+	for s.pos < len(s.src) {
+		rest := s.src[s.pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return "", errors.New("unterminated comment")
+			}
+			comment = strings.TrimSpace(rest[2 : end+2])
+			s.pos += end + 4
+
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			comment = strings.TrimSpace(rest[2:end])
+			s.pos += end
+
+		case strings.IndexByte(" \t\r\n", rest[0]) >= 0:
+			s.pos++
+
+		default:
+			return comment, nil
+		}
+	}
+
+	return comment, nil
+}
+
+// entry scans a "key" = "value"; entry.  An entry without a value uses its key as the value.
+func (s *stringsScanner) entry() (key, value string, err error) {
+	if key, err = s.token(); err != nil {
+		return "", "", err
+	}
+
+	if _, err = s.skipSpace(); err != nil {
+		return "", "", err
+	}
+	if s.consume(';') {
+		return key, key, nil
+	}
+
+	if !s.consume('=') {
+		return "", "", errors.New("expected =")
+	}
+
+	if _, err = s.skipSpace(); err != nil {
+		return "", "", err
+	}
+	if value, err = s.token(); err != nil {
+		return "", "", err
+	}
+
+	if _, err = s.skipSpace(); err != nil {
+		return "", "", err
+	}
+	if !s.consume(';') {
+		return "", "", errors.New("expected ;")
+	}
+
+	return key, value, nil
+}
+
+func (s *stringsScanner) consume(c byte) bool {
+	if s.pos < len(s.src) && s.src[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// token scans a quoted string or an unquoted word.
+func (s *stringsScanner) token() (string, error) {
+	if !s.consume('"') {
+		start := s.pos
+		for s.pos < len(s.src) && isWordChar(s.src[s.pos]) {
+			s.pos++
+		}
+		if s.pos == start {
+			return "", errors.New("expected string")
+		}
+		return s.src[start:s.pos], nil
+	}
+
+	var b strings.Builder
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		s.pos++
+
+		switch {
+		case c == '"':
+			return b.String(), nil
+
+		case c == '\\' && s.pos < len(s.src):
+			e := s.src[s.pos]
+			s.pos++
+
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'U', 'u':
+				if s.pos+4 > len(s.src) {
+					return "", errors.New("bad unicode escape")
+				}
+				r, err := strconv.ParseUint(s.src[s.pos:s.pos+4], 16, 32)
+				if err != nil {
+					return "", errors.New("bad unicode escape")
+				}
+				b.WriteRune(rune(r))
+				s.pos += 4
+			default:
+				b.WriteByte(e)
+			}
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", errors.New("unterminated string")
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_.-$:/", c) >= 0
+}
+
+// quoteStrings returns s as a quoted .strings string.
+func quoteStrings(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	b.WriteByte('"')
+	return b.String()
+}
+
+// WriteStrings writes the plain texts of the file as a UTF-8 .strings file ordered by key.
+// Texts with a Plural version or plural category variants are not written, see WriteStringsDict.
+// If encode is nil the String version of each id is used.
+func WriteStrings(w io.Writer, f *File, encode TextIDEncoder) error {
+	bw := bufio.NewWriter(w)
+
+	for _, e := range f.entries(encode) {
+		if e.quantities != nil {
+			continue
+		}
+
+		if comment, ok := f.Comments[e.id]; ok {
+			fmt.Fprintf(bw, "/* %s */\n", strings.ReplaceAll(comment, "*/", "* /"))
+		}
+
+		text := convertVerbs(f.Texts[e.id], goDialect, appleDialect)
+		fmt.Fprintf(bw, "%s = %s;\n\n", quoteStrings(e.name), quoteStrings(text))
+	}
+
+	return bw.Flush()
+}
+
+// ReadStringsDict reads an Apple .stringsdict plural dictionary, decoding each key using decode.
+// Entries whose localized format has no variables are read as plain texts, entries may have at most
+// one plural rule variable.
+func ReadStringsDict(r io.Reader, decode lpax.TextIDDecoder) (*File, error) {
+	d := xml.NewDecoder(r)
+
+	root, err := readPlist(d)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newFile()
+	for key, v := range root {
+		if err := f.addStringsDictEntry(key, v, decode); err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+	}
+
+	return f, nil
+}
+
+// addStringsDictEntry adds the texts of an entry to the file.
+func (f *File) addStringsDictEntry(key string, v interface{}, decode lpax.TextIDDecoder) error {
+	entry, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("entry is not a dictionary")
+	}
+
+	format, ok := entry[formatKey].(string)
+	if !ok {
+		return fmt.Errorf("missing %s", formatKey)
+	}
+
+	id, err := decode(key)
+	if err != nil {
+		return err
+	}
+
+	matches := stringsdictVariable.FindAllStringSubmatchIndex(format, -1)
+	switch len(matches) {
+	case 0:
+		f.Texts[id.Single()] = convertVerbs(format, appleDialect, goDialect)
+		return nil
+	case 1:
+	default:
+		return errors.New("only one plural variable is supported")
+	}
+
+	m := matches[0]
+	variable, ok := entry[format[m[2]:m[3]]].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("missing variable %q", format[m[2]:m[3]])
+	}
+
+	if variable[specTypeKey] != pluralRuleKey {
+		return fmt.Errorf("variable %q is not a plural rule", format[m[2]:m[3]])
+	}
+
+	for name, text := range variable {
+		s, ok := text.(string)
+		if !ok || name == specTypeKey || name == valueTypeKey {
+			continue
+		}
+
+		key, err := quantityTextID(id, name)
+		if err != nil {
+			return err
+		}
+
+		f.Texts[key] = convertVerbs(format[:m[0]]+s+format[m[1]:], appleDialect, goDialect)
+	}
+
+	return nil
+}
+
+// readPlist reads the root dictionary of a property list.
+func readPlist(d *xml.Decoder) (map[string]interface{}, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			v, err := readPlistValue(d, start)
+			if err != nil {
+				return nil, err
+			}
+
+			dict, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("property list root is not a dictionary")
+			}
+			return dict, nil
+		}
+	}
+}
+
+// readPlistValue reads a dictionary or string value, other values are skipped and returned as nil.
+func readPlistValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "string":
+		var s string
+		err := d.DecodeElement(&s, &start)
+		return s, err
+
+	case "dict":
+		dict := make(map[string]interface{})
+		key := ""
+
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := tok.(type) {
+			case xml.EndElement:
+				return dict, nil
+
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := d.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				v, err := readPlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			}
+		}
+	}
+
+	return nil, d.Skip()
+}
+
+// WriteStringsDict writes the texts of the file with a Plural version or plural category variants
+// as an Apple .stringsdict plural dictionary ordered by key.  Plain texts are not written, see WriteStrings.
+// If encode is nil the String version of each id is used.
+func WriteStringsDict(w io.Writer, f *File, encode TextIDEncoder) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	bw.WriteString("<plist version=\"1.0\">\n<dict>\n")
+
+	writeString := func(indent, key, value string) {
+		fmt.Fprintf(bw, "%s<key>%s</key>\n%s<string>%s</string>\n", indent, html.EscapeString(key), indent, html.EscapeString(value))
+	}
+
+	for _, e := range f.entries(encode) {
+		if e.quantities == nil {
+			continue
+		}
+
+		fmt.Fprintf(bw, "\t<key>%s</key>\n\t<dict>\n", html.EscapeString(e.name))
+		writeString("\t\t", formatKey, "%#@"+pluralVariable+"@")
+		fmt.Fprintf(bw, "\t\t<key>%s</key>\n\t\t<dict>\n", pluralVariable)
+		writeString("\t\t\t", specTypeKey, pluralRuleKey)
+		writeString("\t\t\t", valueTypeKey, "ld")
+
+		for _, q := range e.quantities {
+			writeString("\t\t\t", q.name, convertVerbs(q.text, goDialect, appleDialect))
+		}
+
+		bw.WriteString("\t\t</dict>\n\t</dict>\n")
+	}
+
+	bw.WriteString("</dict>\n</plist>\n")
+
+	return bw.Flush()
+}
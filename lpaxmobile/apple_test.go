@@ -0,0 +1,240 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxmobile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const localizableStrings = `/* Shown on launch */
+"hello" = "Hello %1$@, it's \"here\"\n\U00e9";
+
+// Count of items
+count = "%lld items at 100%%";
+"same";
+`
+
+func TestReadStrings(t *testing.T) {
+	f, err := ReadStrings(strings.NewReader(localizableStrings), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[lpax.TextID]string{
+		testTextID("hello"): "Hello %[1]v, it's \"here\"\né",
+		testTextID("count"): "%d items at 100%%",
+		testTextID("same"):  "same",
+	} {
+		if got := f.Texts[id]; got != want {
+			t.Errorf("%s got %q want %q", id, got, want)
+		}
+	}
+
+	if f.Comments[testTextID("hello")] != "Shown on launch" || f.Comments[testTextID("count")] != "Count of items" || len(f.Comments) != 2 {
+		t.Error("comments", f.Comments)
+	}
+}
+
+func TestReadStringsUTF16(t *testing.T) {
+	encoded, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(localizableStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ReadStrings(strings.NewReader(encoded), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Texts[testTextID("count")] != "%d items at 100%%" {
+		t.Error("texts", f.Texts)
+	}
+}
+
+func TestReadStringsErrors(t *testing.T) {
+	for _, doc := range []string{
+		`"a" = "b"`,
+		`"a" "b";`,
+		`"a" = ;`,
+		`"a" = "b`,
+		`"a" = "\U00";`,
+		`"a" = "\Uzzzz";`,
+		`= "b";`,
+		`"-bad" = "b";`,
+		`/*`,
+		`/* a *`,
+		`"a" /* = "b";`,
+	} {
+		if _, err := ReadStrings(strings.NewReader(doc), decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestWriteStrings(t *testing.T) {
+	f := &File{
+		Texts: lpax.TextMap{
+			testTextID("hello"):  "Hello %[1]v, \"%d\"\n",
+			testTextID("files"):  "%d file",
+			testTextID("-files"): "%d files",
+		},
+		Comments: map[lpax.TextID]string{testTextID("hello"): "greeting */"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteStrings(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/* greeting * / */\n\"hello\" = \"Hello %1$@, \\\"%ld\\\"\\n\";\n\n"
+	if buf.String() != want {
+		t.Fatalf("got %q want %q", buf.String(), want)
+	}
+
+	read, err := ReadStrings(&buf, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Texts[testTextID("hello")] != "Hello %[1]v, \"%d\"\n" {
+		t.Error("round trip", read.Texts)
+	}
+}
+
+const localizableStringsDict = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>files</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>Found %#@files@ in %2$@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>ld</string>
+			<key>zero</key>
+			<string>no files</string>
+			<key>one</key>
+			<string>%ld file</string>
+			<key>other</key>
+			<string>%ld files</string>
+		</dict>
+		<key>comment</key>
+		<integer>1</integer>
+	</dict>
+	<key>plain</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>Plain %@</string>
+	</dict>
+</dict>
+</plist>
+`
+
+func TestReadStringsDict(t *testing.T) {
+	f, err := ReadStringsDict(strings.NewReader(localizableStringsDict), decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[lpax.TextID]string{
+		testTextID("files"):  "Found %d file in %[2]v",
+		testTextID("-files"): "Found %d files in %[2]v",
+		lpax.ByCategory(testTextID("files"), lpax.PluralZero): "Found no files in %[2]v",
+		testTextID("plain"): "Plain %v",
+	} {
+		if got := f.Texts[id]; got != want {
+			t.Errorf("%s got %q want %q", id, got, want)
+		}
+	}
+
+	if len(f.Texts) != 4 {
+		t.Error("texts", f.Texts)
+	}
+}
+
+func TestReadStringsDictErrors(t *testing.T) {
+	entry := func(body string) string {
+		return `<plist version="1.0"><dict><key>e</key>` + body + `</dict></plist>`
+	}
+
+	for _, doc := range []string{
+		`<plist version="1.0"><string>x</string></plist>`,
+		`<plist version="1.0"><dict><key>e</key>`,
+		entry(`<string>x</string>`),
+		entry(`<dict></dict>`),
+		entry(`<dict><key>NSStringLocalizedFormatKey</key><string>%#@a@ %#@b@</string></dict>`),
+		entry(`<dict><key>NSStringLocalizedFormatKey</key><string>%#@a@</string></dict>`),
+		entry(`<dict><key>NSStringLocalizedFormatKey</key><string>%#@a@</string><key>a</key><dict></dict></dict>`),
+		entry(`<dict><key>NSStringLocalizedFormatKey</key><string>%#@a@</string><key>a</key><dict>` +
+			`<key>NSStringFormatSpecTypeKey</key><string>NSStringPluralRuleType</string><key>lots</key><string>x</string></dict></dict>`),
+		`<plist version="1.0"><dict><key>-bad</key><dict><key>NSStringLocalizedFormatKey</key><string>x</string></dict></dict></plist>`,
+	} {
+		if _, err := ReadStringsDict(strings.NewReader(doc), decodeTestTextID); err == nil {
+			t.Error("no error", doc)
+		}
+	}
+}
+
+func TestWriteStringsDict(t *testing.T) {
+	f := &File{
+		Texts: lpax.TextMap{
+			testTextID("hello"):  "not written",
+			testTextID("files"):  "%d file & more",
+			testTextID("-files"): "%d files",
+			lpax.ByCategory(testTextID("files"), lpax.PluralFew): "%d few",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteStringsDict(&buf, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	for _, want := range []string{
+		"<key>files</key>",
+		"<string>%#@value@</string>",
+		"<key>one</key>\n\t\t\t<string>%ld file &amp; more</string>",
+		"<key>few</key>\n\t\t\t<string>%ld few</string>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Error("missing", want, s)
+		}
+	}
+
+	if strings.Contains(s, "hello") {
+		t.Error("plain text written", s)
+	}
+
+	read, err := ReadStringsDict(&buf, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read.Texts) != 3 || read.Texts[testTextID("files")] != "%d file & more" || read.Texts[testTextID("-files")] != "%d files" {
+		t.Error("round trip", read.Texts)
+	}
+}
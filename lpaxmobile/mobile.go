@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxmobile exchanges lpax TextMaps with Android strings.xml resources and
+// Apple .strings and .stringsdict files.
+//
+// Plain strings are the Single version of their key's TextID.  Plural quantities are mapped
+// with one as the Single version, other as the Plural version and the zero, two, few and
+// many quantities as lpax.ByCategory variants.
+//
+// Java and Apple format specifiers such as %1$s, %@ and %ld are converted to the equivalent
+// fmt verbs, e.g. %[1]v, %v and %d, when read and back when written.
+//
+// Android styled strings keep their markup, e.g. <b> or <xliff:g>, in the text so it survives a
+// round trip.  Escaped < and > characters are read as text, they are written escaped unless they form
+// a tag that is closed.
+package lpaxmobile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nehemming/lpax"
+)
+
+type (
+	// TextIDEncoder encodes a Single TextID as a string name or key.
+	// The encoding must be reversible by the TextIDDecoder used to read the file back.
+	TextIDEncoder func(id lpax.TextID) string
+
+	// File holds the texts of a strings file.
+	File struct {
+		// Texts are the texts of the file.
+		Texts lpax.TextMap
+
+		// Comments are translator comments keyed by the Single TextID of the text they describe.
+		Comments map[lpax.TextID]string
+	}
+
+	// quantity is a plural quantity of a text.
+	quantity struct {
+		name string
+		text string
+	}
+
+	// entry is the encoded name and texts of a Single TextID.
+	entry struct {
+		name       string
+		id         lpax.TextID
+		quantities []quantity // nil for plain strings
+	}
+)
+
+// variantCategories are the plural categories stored as ByCategory variants.
+var variantCategories = []lpax.PluralCategory{lpax.PluralZero, lpax.PluralTwo, lpax.PluralFew, lpax.PluralMany}
+
+// newFile returns an empty file.
+func newFile() *File {
+	return &File{Texts: make(lpax.TextMap), Comments: make(map[lpax.TextID]string)}
+}
+
+// quantityTextID returns the key of the text of a plural quantity.
+func quantityTextID(id lpax.TextID, name string) (lpax.TextID, error) {
+	switch name {
+	case "one":
+		return id.Single(), nil
+	case "other":
+		return id.Plural(), nil
+	}
+
+	for _, c := range variantCategories {
+		if c.String() == name {
+			return lpax.ByCategory(id, c), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown quantity %q", name)
+}
+
+// entries returns the texts of the file ordered by encoded name.  Texts with a Plural
+// version or plural category variants have quantities, other texts are plain strings.
+// Ordinal variants are not included.
+func (f *File) entries(encode TextIDEncoder) []entry {
+	if encode == nil {
+		encode = func(id lpax.TextID) string { return id.String() }
+	}
+
+	distinct := make(map[lpax.TextID]bool)
+	entries := make([]entry, 0, len(f.Texts))
+
+	for k := range f.Texts {
+		id := k.Single()
+		if distinct[id] {
+			continue
+		}
+		distinct[id] = true
+
+		e := entry{name: encode(id), id: id, quantities: f.quantities(id)}
+		switch {
+		case len(e.quantities) == 0:
+			// only ordinal variants
+			continue
+		case len(e.quantities) == 1 && e.quantities[0].name == "one":
+			e.quantities = nil
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
+
+// quantities returns the plural quantities of id in CLDR order.
+func (f *File) quantities(id lpax.TextID) []quantity {
+	var quantities []quantity
+
+	add := func(name string, key lpax.TextID) {
+		if text, ok := f.Texts[key]; ok {
+			quantities = append(quantities, quantity{name, text})
+		}
+	}
+
+	add("zero", lpax.ByCategory(id, lpax.PluralZero))
+	add("one", id.Single())
+	add("two", lpax.ByCategory(id, lpax.PluralTwo))
+	add("few", lpax.ByCategory(id, lpax.PluralFew))
+	add("many", lpax.ByCategory(id, lpax.PluralMany))
+	add("other", id.Plural())
+
+	return quantities
+}
@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxmobile
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nehemming/lpax/internal/printf"
+)
+
+// dialect is a printf format dialect.
+type dialect int
+
+const (
+	goDialect = dialect(iota)
+	javaDialect
+	appleDialect
+)
+
+const (
+	// flagChars are the flag, width and precision characters shared by the dialects.
+	flagChars = "+-# 0123456789.*,("
+
+	// appleLengthChars are the length modifier characters of Apple format strings.
+	appleLengthChars = "hlqLztj"
+)
+
+// verb is a parsed format verb.
+type verb struct {
+	index int    // explicit argument index, zero if none
+	flags string // flags, width and precision
+	verb  byte
+}
+
+// convertVerbs converts the format verbs of s from one dialect to another.
+// Text that is not a verb is unchanged.
+func convertVerbs(s string, from, to dialect) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		v, end, ok := parseVerb(s, i, from)
+		if !ok {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		b.WriteString(v.format(from, to))
+		i = end - 1
+	}
+
+	return b.String()
+}
+
+// parseVerb parses the verb starting with the % at start, returning the offset of the byte following
+// the verb.  Go verbs are parsed by the fmt parser shared with the other formats so they match fmt.
+func parseVerb(s string, start int, d dialect) (v verb, end int, ok bool) {
+	if d == goDialect {
+		pv := printf.ParseVerb(s, start, 0)
+		if pv.Verb == 0 || pv.BadIndex || pv.Verb >= utf8.RuneSelf {
+			return v, 0, false
+		}
+		return verb{index: pv.Index, flags: pv.Spec, verb: byte(pv.Verb)}, pv.End, true
+	}
+
+	s = s[start+1:]
+	n := 0
+
+	// positional n$
+	j := 0
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+	}
+	if j > 0 && j < len(s) && s[j] == '$' {
+		v.index, _ = strconv.Atoi(s[:j])
+		n = j + 1
+	}
+
+	for n < len(s) {
+		c := s[n]
+		switch {
+		case strings.IndexByte(flagChars, c) >= 0:
+			v.flags += string(c)
+			n++
+		case d == appleDialect && strings.IndexByte(appleLengthChars, c) >= 0:
+			// length modifiers are implied by the Go value
+			n++
+		default:
+			v.verb = c
+			return v, start + n + 2, true
+		}
+	}
+
+	return v, 0, false
+}
+
+// format formats the verb in the to dialect.
+func (v verb) format(from, to dialect) string {
+	if v.verb == '%' {
+		return "%%"
+	}
+
+	if from == javaDialect && v.verb == 'n' {
+		return "\n"
+	}
+
+	c := v.verb
+	switch {
+	case to == goDialect && (c == '@' || (from == javaDialect && c == 's')):
+		c = 'v'
+	case to == goDialect && from == javaDialect && c == 'b':
+		c = 't'
+	case to != goDialect && (c == 'v' || c == 'q'):
+		c = 's'
+	case to == javaDialect && c == 't':
+		c = 'b'
+	}
+
+	var b strings.Builder
+	b.WriteByte('%')
+
+	if to == goDialect {
+		if v.index > 0 {
+			b.WriteString("[" + strconv.Itoa(v.index) + "]")
+		}
+		b.WriteString(v.flags)
+		b.WriteByte(c)
+		return b.String()
+	}
+
+	if v.index > 0 {
+		b.WriteString(strconv.Itoa(v.index) + "$")
+	}
+	b.WriteString(v.flags)
+
+	if to == appleDialect {
+		switch c {
+		case 's':
+			c = '@'
+		case 'd', 'i', 'u', 'x', 'X', 'o':
+			b.WriteByte('l')
+		}
+	}
+
+	b.WriteByte(c)
+	return b.String()
+}
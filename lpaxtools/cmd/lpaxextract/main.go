@@ -0,0 +1,104 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command lpaxextract lists the lpax TextIDs declared by the packages of a Go module, where they are
+// used and their registered default language text.
+//
+// Usage:
+//
+//	lpaxextract [-C dir] [-json] [-tests] [-text=false] [packages]
+//
+// The packages default to ./...
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/nehemming/lpax/lpaxtools/lpaxextract"
+)
+
+func main() {
+	if err := run(os.Stdout, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "lpaxextract:", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, args []string) error {
+	flags := flag.NewFlagSet("lpaxextract", flag.ContinueOnError)
+	dir := flags.String("C", "", "directory to run in")
+	asJSON := flags.Bool("json", false, "write the report as JSON")
+	tests := flags.Bool("tests", false, "include test files")
+	text := flags.Bool("text", true, "look up the registered default language text, running the init functions of the packages")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := lpaxextract.Extract(lpaxextract.Config{
+		Dir:      *dir,
+		Patterns: flags.Args(),
+		Tests:    *tests,
+		Text:     *text,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	return writeText(w, report)
+}
+
+// writeText writes a tab aligned line for each id followed by its uses.
+func writeText(w io.Writer, report *lpaxextract.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, id := range report.IDs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", id.Code, id, id.Position, quote(id.Single))
+
+		if id.Plural != "" {
+			fmt.Fprintf(tw, "\t\tplural\t%s\n", quote(id.Plural))
+		}
+
+		for _, use := range id.Uses {
+			fmt.Fprintf(tw, "\t\t%s\t%s\n", use.Function, use.Position)
+		}
+	}
+
+	for _, use := range report.Dynamic {
+		fmt.Fprintf(tw, "\t%s\t%s\t%s\n", use.Expression, use.Function, use.Position)
+	}
+
+	return tw.Flush()
+}
+
+func quote(s string) string {
+	if s == "" {
+		return ""
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testApp = "../../lpaxextract/testdata/app"
+
+func TestMain(m *testing.M) {
+	// the test app is a module of its own, outside any go.work workspace the repository is developed in
+	os.Setenv("GOWORK", "off")
+	os.Exit(m.Run())
+}
+
+func TestRunText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run(&buf, []string{"-C", testApp}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	for _, want := range []string{"messages-00001", "example.com/app/messages.Hello", `"Hello World"`, `"%d files"`, "Errorf", "id"} {
+		if !strings.Contains(s, want) {
+			t.Error("missing", want, s)
+		}
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run(&buf, []string{"-C", testApp, "-json", "-text=false", "./messages"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		IDs []struct {
+			Name string `json:"name"`
+		} `json:"ids"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil || len(report.IDs) != 3 {
+		t.Error("json", err, buf.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{{"-unknown"}, {"-C", testApp, "./missing"}} {
+		if err := run(&bytes.Buffer{}, args); err == nil {
+			t.Error("no error", args)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command lpaxgen generates the Go source of an lpax language pack from a YAML or JSON catalog.
+//
+// It is typically run by go generate:
+//
+//	//go:generate go run github.com/nehemming/lpax/lpaxtools/cmd/lpaxgen messages.yaml
+//
+// The source is written to the catalog's name with a _gen.go suffix unless -o is given.  The package
+// name defaults to the catalog's package, or the $GOPACKAGE set by go generate.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nehemming/lpax/lpaxtools/lpaxgen"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "lpaxgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("lpaxgen", flag.ContinueOnError)
+	output := flags.String("o", "", "output file, defaults to the catalog name with a _gen.go suffix")
+	pkg := flags.String("package", "", "package name, defaults to the catalog's package or $GOPACKAGE")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: lpaxgen [-o output] [-package name] catalog.yaml")
+	}
+
+	name := flags.Arg(0)
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c, err := lpaxgen.ReadCatalog(f, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case *pkg != "":
+		c.Package = *pkg
+	case c.Package == "":
+		c.Package = os.Getenv("GOPACKAGE")
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(name, filepath.Ext(name)) + "_gen.go"
+	}
+
+	var buf bytes.Buffer
+	if err := lpaxgen.Generate(&buf, c, filepath.Base(name)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return ioutil.WriteFile(*output, buf.Bytes(), 0o644)
+}
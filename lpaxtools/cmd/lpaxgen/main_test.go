@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	catalog := filepath.Join(dir, "messages.yml")
+
+	if err := ioutil.WriteFile(catalog, []byte("texts:\n  - name: Hello\n    single:\n      en: Hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("GOPACKAGE", "generated")
+	defer os.Unsetenv("GOPACKAGE")
+
+	if err := run([]string{catalog}); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ioutil.ReadFile(filepath.Join(dir, "messages_gen.go"))
+	if err != nil || !strings.Contains(string(src), "package generated") {
+		t.Error("generated", err)
+	}
+
+	output := filepath.Join(dir, "other.go")
+	if err := run([]string{"-o", output, "-package", "other", catalog}); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err = ioutil.ReadFile(output)
+	if err != nil || !strings.Contains(string(src), "package other") {
+		t.Error("output", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.yaml")
+
+	if err := ioutil.WriteFile(bad, []byte("texts: 1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-unknown"},
+		{filepath.Join(dir, "missing.yaml")},
+		{bad},
+		{"-package", "p", filepath.Join("..", "..", "lpaxgen", "generate.go")},
+	} {
+		if err := run(args); err == nil {
+			t.Error("no error", args)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command lpaxvet reports misuse of the lpax package, see the lpaxvet package for the checks made.
+//
+// It may be run directly or by go vet:
+//
+//	go install github.com/nehemming/lpax/lpaxtools/cmd/lpaxvet
+//	go vet -vettool=$(which lpaxvet) ./...
+package main
+
+import (
+	"github.com/nehemming/lpax/lpaxtools/lpaxvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(lpaxvet.Analyzer)
+}
@@ -0,0 +1,18 @@
+module github.com/nehemming/lpax/lpaxtools
+
+go 1.25.0
+
+require (
+	github.com/nehemming/lpax v0.0.0-20261018043950-a265a5a2b494
+	golang.org/x/text v0.37.0
+	golang.org/x/tools v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cloudfoundry-attic/jibber_jabber v0.0.0-20151120183258-bcc4c8345a21 // indirect
+	github.com/nxadm/tail v1.4.11 // indirect
+	golang.org/x/mod v0.36.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
@@ -0,0 +1,61 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package textid resolves the lpax TextID expressions shared by the lpax tools.
+package textid
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// LpaxPath is the import path of the lpax package.
+const LpaxPath = "github.com/nehemming/lpax"
+
+// ResolveConst resolves an id argument to the constant it refers to.  The argument may be
+// the constant, its negation or a call to its Single or Plural method.
+func ResolveConst(info *types.Info, arg ast.Expr) (c *types.Const, plural bool) {
+	for {
+		switch x := arg.(type) {
+		case *ast.ParenExpr:
+			arg = x.X
+			continue
+
+		case *ast.UnaryExpr:
+			if x.Op != token.SUB {
+				return nil, false
+			}
+			arg, plural = x.X, !plural
+			continue
+
+		case *ast.CallExpr:
+			sel, ok := x.Fun.(*ast.SelectorExpr)
+			if !ok || len(x.Args) != 0 || (sel.Sel.Name != "Plural" && sel.Sel.Name != "Single") {
+				return nil, false
+			}
+			arg, plural = sel.X, sel.Sel.Name == "Plural"
+			continue
+
+		case *ast.SelectorExpr:
+			c, _ = info.Uses[x.Sel].(*types.Const)
+		case *ast.Ident:
+			c, _ = info.Uses[x].(*types.Const)
+		}
+
+		return c, plural
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package textid
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+const src = `package p
+
+type T int
+
+func (t T) Single() T { return t }
+func (t T) Plural() T { return -t }
+
+const A = T(1)
+
+var ids = []T{A, -A, (A).Plural(), A.Single(), -(-A), A + 1}
+`
+
+func TestResolveConst(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	if _, err := new(types.Config).Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	var elts []ast.Expr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			elts = lit.Elts
+		}
+		return true
+	})
+
+	tests := []struct {
+		found  bool
+		plural bool
+	}{
+		{true, false}, {true, true}, {true, true}, {true, false}, {true, false}, {false, false},
+	}
+
+	if len(elts) != len(tests) {
+		t.Fatal("elements", len(elts))
+	}
+
+	for i, test := range tests {
+		c, plural := ResolveConst(info, elts[i])
+		if (c != nil) != test.found || plural != test.plural || (c != nil && c.Name() != "A") {
+			t.Error(i, c, plural)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxextract lists the lpax TextIDs declared and used by the packages of a Go module.
+//
+// The packages are type checked to find every constant whose type implements lpax.TextID and every
+// call to an lpax formatting function such as Sprintf, Errorf, CtxSprintf and CtxErrorf.  Constants
+// used by the packages but declared by packages outside the patterns are reported as external ids.
+//
+// The text registered for the ids in the lpax.DefaultLanguage is found by generating a program that
+// imports the packages declaring them and running it with go run in the module of the packages
+// importing them.  Running the program runs the init functions of those packages and their imports,
+// so text should only be looked up for trusted code.  The program is added to the module as a build
+// overlay, no files are written to the module.
+package lpaxextract
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/nehemming/lpax/lpaxtools/internal/textid"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+type (
+	// Config configures the packages extracted.
+	Config struct {
+		// Dir is the directory the patterns are resolved in, defaulting to the current directory.
+		Dir string
+
+		// Patterns are the go package patterns to extract, defaulting to ./...
+		Patterns []string
+
+		// Tests includes test files.
+		Tests bool
+
+		// Text looks up the registered text of the ids by running a generated program that imports the
+		// packages declaring them, running their init functions.
+		Text bool
+	}
+
+	// ID is a TextID constant.
+	ID struct {
+		// Package is the import path of the package declaring the constant.
+		Package string `json:"package"`
+
+		// Name is the name of the constant.
+		Name string `json:"name"`
+
+		// Type is the name of the constant's type.
+		Type string `json:"type"`
+
+		// Value is the constant's value.
+		Value string `json:"value"`
+
+		// Position is the source location of the constant's declaration.
+		Position string `json:"position"`
+
+		// External is true if the constant is declared by a package outside the patterns extracted.
+		External bool `json:"external,omitempty"`
+
+		// Code, Single and Plural are the id's error code and the Single and Plural text registered in the
+		// default language.  They are only set if the text was looked up and the constant can be imported.
+		Code   string `json:"code,omitempty"`
+		Single string `json:"single,omitempty"`
+		Plural string `json:"plural,omitempty"`
+
+		// Uses are the calls passing the id to an lpax function.
+		Uses []Call `json:"uses,omitempty"`
+
+		object   *types.Const
+		position token.Position
+	}
+
+	// Call is a call to an lpax function taking a TextID.
+	Call struct {
+		// Function is the name of the lpax function called.
+		Function string `json:"function"`
+
+		// Position is the source location of the call.
+		Position string `json:"position"`
+
+		// Plural is true if the Plural version of the id is passed.
+		Plural bool `json:"plural,omitempty"`
+
+		// Expression is the source of a TextID argument that is not a constant.
+		Expression string `json:"expression,omitempty"`
+	}
+
+	// Report lists the ids found.
+	Report struct {
+		// IDs are the TextID constants ordered by position.
+		IDs []*ID `json:"ids"`
+
+		// Dynamic are the calls passing a TextID that is not a constant.
+		Dynamic []Call `json:"dynamic,omitempty"`
+	}
+)
+
+// Extract loads and type checks the packages and reports the TextIDs they declare and use.
+func Extract(cfg Config) (*Report, error) {
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedImports | packages.NeedModule,
+		Dir:   cfg.Dir,
+		Tests: cfg.Tests,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadErrors(pkgs); err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+
+	textID := findTextID(pkgs)
+	if textID == nil {
+		// nothing uses lpax
+		return report, nil
+	}
+
+	e := &extractor{report: report, textID: textID, ids: make(map[string]*ID), files: make(map[string]bool)}
+	for _, pkg := range pkgs {
+		e.declarations(pkg)
+	}
+
+	for _, pkg := range pkgs {
+		e.calls(pkg)
+	}
+
+	sort.Slice(report.IDs, func(i, j int) bool {
+		a, b := report.IDs[i].position, report.IDs[j].position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+
+	if cfg.Text {
+		if err := lookupText(pkgs, report.IDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// loadErrors returns the errors loading the packages.
+func loadErrors(pkgs []*packages.Package) error {
+	var msgs []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			msgs = append(msgs, err.Error())
+		}
+	})
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+
+	return nil
+}
+
+// findTextID finds the lpax TextID type in the packages or their imports.
+func findTextID(pkgs []*packages.Package) types.Type {
+	var textID types.Type
+	seen := make(map[*types.Package]bool)
+
+	var find func(p *types.Package)
+	find = func(p *types.Package) {
+		if textID != nil || seen[p] {
+			return
+		}
+		seen[p] = true
+
+		if p.Path() == textid.LpaxPath {
+			if obj := p.Scope().Lookup("TextID"); obj != nil {
+				textID = obj.Type()
+			}
+			return
+		}
+
+		for _, imp := range p.Imports() {
+			find(imp)
+		}
+	}
+
+	for _, pkg := range pkgs {
+		find(pkg.Types)
+	}
+
+	return textID
+}
+
+type extractor struct {
+	report *Report
+	textID types.Type
+	ids    map[string]*ID  // keyed by qualified name as test variants of a package declare the same ids
+	files  map[string]bool // files whose calls have been reported, test variants of a package repeat its files
+}
+
+// implements reports if t implements the lpax TextID interface.
+func (e *extractor) implements(t types.Type) bool {
+	iface, ok := e.textID.Underlying().(*types.Interface)
+	if !ok || types.IsInterface(t) {
+		return false
+	}
+	return types.Implements(t, iface)
+}
+
+// declarations adds the TextID constants declared by the package.
+func (e *extractor) declarations(pkg *packages.Package) {
+	for ident, obj := range pkg.TypesInfo.Defs {
+		c, ok := obj.(*types.Const)
+		if !ok || !e.implements(c.Type()) {
+			continue
+		}
+
+		// ignore constants local to functions
+		if c.Parent() != c.Pkg().Scope() || e.ids[qualifiedName(c)] != nil {
+			continue
+		}
+
+		e.add(c, pkg.Fset.Position(ident.Pos()))
+	}
+}
+
+// add adds the id of a constant declared at position.
+func (e *extractor) add(c *types.Const, position token.Position) *ID {
+	id := &ID{
+		Package:  c.Pkg().Path(),
+		Name:     c.Name(),
+		Type:     types.TypeString(c.Type(), types.RelativeTo(c.Pkg())),
+		Value:    c.Val().ExactString(),
+		Position: position.String(),
+		object:   c,
+		position: position,
+	}
+
+	e.ids[qualifiedName(c)] = id
+	e.report.IDs = append(e.report.IDs, id)
+
+	return id
+}
+
+// calls adds the calls to lpax functions taking a TextID made by the package.
+func (e *extractor) calls(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		name := pkg.Fset.Position(file.Pos()).Filename
+		if e.files[name] {
+			continue
+		}
+		e.files[name] = true
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			fn, ok := typeutil.Callee(pkg.TypesInfo, call).(*types.Func)
+			if !ok || fn.Pkg() == nil || fn.Pkg().Path() != textid.LpaxPath {
+				return true
+			}
+
+			arg := e.textIDArg(fn, call)
+			if arg == nil {
+				return true
+			}
+
+			c, plural := textid.ResolveConst(pkg.TypesInfo, arg)
+			use := Call{
+				Function: fn.Name(),
+				Position: pkg.Fset.Position(call.Pos()).String(),
+				Plural:   plural,
+			}
+
+			if c == nil {
+				use.Expression = types.ExprString(arg)
+				e.report.Dynamic = append(e.report.Dynamic, use)
+				return true
+			}
+
+			id := e.ids[qualifiedName(c)]
+			if id == nil {
+				// declared by a package outside the patterns, its position is known from the type information
+				id = e.add(c, pkg.Fset.Position(c.Pos()))
+				id.External = true
+			}
+
+			id.Uses = append(id.Uses, use)
+			return true
+		})
+	}
+}
+
+// textIDArg returns the TextID argument of a call to an lpax function.
+func (e *extractor) textIDArg(fn *types.Func, call *ast.CallExpr) ast.Expr {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil {
+		return nil
+	}
+
+	params := sig.Params()
+	for i := 0; i < params.Len() && i < len(call.Args); i++ {
+		if types.Identical(params.At(i).Type(), e.textID) {
+			return call.Args[i]
+		}
+	}
+
+	return nil
+}
+
+// qualifiedName returns the import path qualified name of a constant.
+func qualifiedName(c *types.Const) string {
+	return c.Pkg().Path() + "." + c.Name()
+}
+
+// String returns the import path qualified name of the id.
+func (id *ID) String() string {
+	return fmt.Sprintf("%s.%s", id.Package, id.Name)
+}
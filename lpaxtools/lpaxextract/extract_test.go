@@ -0,0 +1,155 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxextract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the test app is a module of its own, outside any go.work workspace the repository is developed in
+	os.Setenv("GOWORK", "off")
+	os.Exit(m.Run())
+}
+
+func findID(report *Report, name string) *ID {
+	for _, id := range report.IDs {
+		if id.Name == name {
+			return id
+		}
+	}
+	return nil
+}
+
+func TestExtract(t *testing.T) {
+	report, err := Extract(Config{Dir: "testdata/app", Text: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.IDs) != 3 || report.IDs[0].Name != "Hello" || report.IDs[1].Name != "Files" || report.IDs[2].Name != "hidden" {
+		t.Fatal("ids", report.IDs)
+	}
+
+	hello := findID(report, "Hello")
+	if hello.Package != "example.com/app/messages" || hello.Type != "TextID" || hello.Value != "1" ||
+		!strings.HasSuffix(hello.Position, "messages.go:20:2") {
+		t.Errorf("hello %+v", hello)
+	}
+
+	if hello.Code != "messages-00001" || hello.Single != "Hello World" || len(hello.Uses) != 1 || hello.Uses[0].Function != "Sprintf" {
+		t.Error("hello text", hello)
+	}
+
+	files := findID(report, "Files")
+	if files.Single != "%d file" || files.Plural != "%d files" || len(files.Uses) != 2 {
+		t.Fatal("files", files)
+	}
+
+	for _, use := range files.Uses {
+		if !use.Plural || (use.Function != "Errorf" && use.Function != "CtxSprintf") {
+			t.Error("files use", use)
+		}
+	}
+
+	hidden := findID(report, "hidden")
+	if hidden.Single != "" || len(hidden.Uses) != 1 {
+		t.Error("unexported", hidden)
+	}
+
+	if len(report.Dynamic) != 1 || report.Dynamic[0].Expression != "id" || !strings.HasSuffix(report.Dynamic[0].Position, "main.go:17:14") {
+		t.Error("dynamic", report.Dynamic)
+	}
+}
+
+func TestExtractWithoutText(t *testing.T) {
+	report, err := Extract(Config{Dir: "testdata/app", Patterns: []string{"./messages"}, Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.IDs) != 3 || report.IDs[0].Single != "" || len(report.Dynamic) != 0 {
+		t.Error("ids", report.IDs)
+	}
+}
+
+func TestExtractTestsReportsUsesOnce(t *testing.T) {
+	report, err := Extract(Config{Dir: "testdata/app", Patterns: []string{"./messages"}, Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the test variant of the package also contains messages.go
+	if hidden := findID(report, "hidden"); len(hidden.Uses) != 1 {
+		t.Error("hidden", hidden.Uses)
+	}
+
+	if hello := findID(report, "Hello"); len(hello.Uses) != 1 || !strings.HasSuffix(hello.Uses[0].Position, "messages_test.go:10:5") {
+		t.Error("hello", hello.Uses)
+	}
+}
+
+func TestExtractExternalIDs(t *testing.T) {
+	report, err := Extract(Config{Dir: "testdata/app", Patterns: []string{"."}, Text: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages package is imported but not extracted
+	if len(report.IDs) != 2 || report.IDs[0].Name != "Hello" || report.IDs[1].Name != "Files" {
+		t.Fatal("ids", report.IDs)
+	}
+
+	hello := findID(report, "Hello")
+	if !hello.External || hello.Single != "Hello World" || len(hello.Uses) != 1 || !strings.Contains(hello.Position, "messages.go:20:") {
+		t.Errorf("hello %+v", hello)
+	}
+
+	if files := findID(report, "Files"); !files.External || files.Plural != "%d files" || len(files.Uses) != 2 {
+		t.Errorf("files %+v", files)
+	}
+
+	if _, err := os.Stat(filepath.Join("testdata/app", lookupDir)); !os.IsNotExist(err) {
+		t.Error("lookup written to module", err)
+	}
+}
+
+func TestImportable(t *testing.T) {
+	for _, tc := range []struct {
+		path, modulePath string
+		importable       bool
+	}{
+		{"example.com/app/messages", "example.com/app", true},
+		{"example.com/app/internal/messages", "example.com/app", true},
+		{"example.com/lib/internal/messages", "example.com/app", false},
+		{"example.com/lib/internal/messages", "example.com/lib/cmd", true},
+		{"example.com/app/internal/a/internal/b", "example.com/app", false},
+	} {
+		if importable(tc.path, tc.modulePath) != tc.importable {
+			t.Error(tc.path, tc.modulePath)
+		}
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	if _, err := Extract(Config{Dir: "testdata/app", Patterns: []string{"./missing"}}); err == nil {
+		t.Error("missing package")
+	}
+}
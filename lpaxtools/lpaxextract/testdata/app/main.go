@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/app/messages"
+	"github.com/nehemming/lpax"
+)
+
+func main() {
+	fmt.Println(lpax.Sprintf(messages.Hello))
+	fmt.Println(lpax.Errorf(messages.Files.Plural(), 2))
+	fmt.Println(lpax.CtxSprintf(context.Background(), -(messages.Files), 3))
+
+	var id lpax.TextID = messages.Hello
+	fmt.Println(lpax.Sprintf(id), messages.Hidden())
+}
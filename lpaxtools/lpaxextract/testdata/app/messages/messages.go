@@ -0,0 +1,38 @@
+package messages
+
+import (
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type (
+	PackID int
+	TextID int
+)
+
+func (id TextID) Single() lpax.TextID { return TextID(lpax.IntTypeSingle(int(id))) }
+func (id TextID) Plural() lpax.TextID { return TextID(lpax.IntTypePlural(int(id))) }
+func (id TextID) String() string      { return lpax.ReflectCoderString(id.Single()) }
+
+const (
+	Pack = PackID(1)
+
+	Hello = TextID(iota)
+	Files
+	hidden
+)
+
+var english = lpax.TextMap{
+	Hello:  "Hello World",
+	Files:  "%d file",
+	-Files: "%d files",
+	hidden: "Hidden",
+}
+
+func init() {
+	lpax.Default().Register(Pack, func(lpax.PackID, lpax.Tag) lpax.TextMap { return english }, lpax.DefaultPriority, language.English)
+}
+
+func Hidden() string {
+	return lpax.Sprintf(hidden)
+}
@@ -0,0 +1,13 @@
+package messages
+
+import (
+	"testing"
+
+	"github.com/nehemming/lpax"
+)
+
+func TestHello(t *testing.T) {
+	if lpax.Sprintf(Hello) == "" {
+		t.Error("hello")
+	}
+}
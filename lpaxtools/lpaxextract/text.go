@@ -0,0 +1,263 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxextract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+type (
+	// lookupImport is a package imported by the lookup program.
+	lookupImport struct {
+		Name string
+		Path string
+	}
+
+	// lookupID is an id looked up by the lookup program.
+	lookupID struct {
+		Key    string
+		Import string
+		Name   string
+	}
+
+	// lookup is the lookup program run in a module.
+	lookup struct {
+		dir      string                       // the module directory
+		path     string                       // the module path
+		packages map[string]*packages.Package // the packages imported by the module's packages, keyed by path
+		imports  map[string]*lookupImport
+		ids      []lookupID
+	}
+
+	// lookupResult is the text of an id output by the lookup program.
+	lookupResult struct {
+		Code   string `json:"code"`
+		Single string `json:"single"`
+		Plural string `json:"plural"`
+	}
+)
+
+// lookupDir is the directory of the module the lookup program is overlaid in.
+const lookupDir = ".lpaxextract"
+
+var lookupTemplate = template.Must(template.New("lookup").Parse(`// Code generated by lpaxextract. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/nehemming/lpax"
+{{- range .Imports}}
+	{{.Name}} {{printf "%q" .Path}}
+{{- end}}
+)
+
+type result struct {
+	Code   string ` + "`json:\"code\"`" + `
+	Single string ` + "`json:\"single\"`" + `
+	Plural string ` + "`json:\"plural\"`" + `
+}
+
+func main() {
+	finder := lpax.Default().New(lpax.DefaultLanguage)
+	results := make(map[string]result)
+
+	add := func(key string, id lpax.TextID) {
+		single, _ := finder.Find(id.Single())
+		plural, _ := finder.Find(id.Plural())
+		results[key] = result{Code: lpax.TextCode(id), Single: single, Plural: plural}
+	}
+{{range .IDs}}
+	add({{printf "%q" .Key}}, {{.Import}}.{{.Name}})
+{{- end}}
+
+	if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+		os.Exit(1)
+	}
+}
+`))
+
+// lookupText looks up the registered text of the ids that can be imported.  A lookup program is run in
+// each module of the packages extracted, looking up the ids declared by the packages the module's packages
+// import, including themselves.
+func lookupText(pkgs []*packages.Package, ids []*ID) error {
+	var lookups []*lookup
+	modules := make(map[string]*lookup)
+
+	for _, pkg := range pkgs {
+		// test variants of packages cannot be imported
+		if pkg.Module == nil || pkg.ID != pkg.PkgPath {
+			continue
+		}
+
+		l, ok := modules[pkg.Module.Dir]
+		if !ok {
+			l = &lookup{
+				dir:      pkg.Module.Dir,
+				path:     pkg.Module.Path,
+				packages: make(map[string]*packages.Package),
+				imports:  make(map[string]*lookupImport),
+			}
+			modules[l.dir] = l
+			lookups = append(lookups, l)
+		}
+
+		packages.Visit([]*packages.Package{pkg}, func(p *packages.Package) bool {
+			if l.packages[p.PkgPath] != nil {
+				return false
+			}
+			l.packages[p.PkgPath] = p
+			return true
+		}, nil)
+	}
+
+	for _, id := range ids {
+		if !token.IsExported(id.Name) || strings.HasSuffix(id.position.Filename, "_test.go") {
+			continue
+		}
+
+		for _, l := range lookups {
+			if l.add(id) {
+				break
+			}
+		}
+	}
+
+	for _, l := range lookups {
+		if len(l.ids) == 0 {
+			continue
+		}
+
+		results, err := l.run()
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if r, ok := results[id.String()]; ok {
+				id.Code, id.Single, id.Plural = r.Code, r.Single, r.Plural
+			}
+		}
+	}
+
+	return nil
+}
+
+// add adds the id to the lookup if the module's packages import the package declaring it and the
+// lookup program can import it.
+func (l *lookup) add(id *ID) bool {
+	pkg := l.packages[id.Package]
+	if pkg == nil || pkg.Name == "main" || !importable(id.Package, l.path) {
+		return false
+	}
+
+	imp, ok := l.imports[id.Package]
+	if !ok {
+		imp = &lookupImport{Name: "p" + strconv.Itoa(len(l.imports)), Path: id.Package}
+		l.imports[id.Package] = imp
+	}
+
+	l.ids = append(l.ids, lookupID{Key: id.String(), Import: imp.Name, Name: id.Name})
+	return true
+}
+
+// importable reports if a package of the module can import the package path.  Packages below an
+// internal directory can only be imported by the packages rooted at the internal directory's parent.
+func importable(path, modulePath string) bool {
+	elems := strings.Split(path, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if elems[i] == "internal" {
+			parent := strings.Join(elems[:i], "/")
+			return parent == "" || modulePath == parent || strings.HasPrefix(modulePath, parent+"/")
+		}
+	}
+
+	return true
+}
+
+// run generates the lookup program and runs it in the module directory.  The program is added to the
+// module using a build overlay so the module directory is unchanged.
+func (l *lookup) run() (map[string]lookupResult, error) {
+	data := struct {
+		Imports []*lookupImport
+		IDs     []lookupID
+	}{IDs: l.ids}
+
+	for _, imp := range l.imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Path < data.Imports[j].Path })
+
+	var src bytes.Buffer
+	if err := lookupTemplate.Execute(&src, data); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "lpaxextract")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(source, src.Bytes(), 0o600); err != nil {
+		return nil, err
+	}
+
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {filepath.Join(l.dir, lookupDir, "main.go"): source},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := ioutil.WriteFile(overlayFile, overlay, 0o600); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "run", "-overlay", overlayFile, "./"+lookupDir)
+	cmd.Dir = l.dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("looking up text in %s: %w\n%s", l.path, err, stderr.String())
+	}
+
+	var results map[string]lookupResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		return nil, fmt.Errorf("looking up text in %s: %w", l.path, err)
+	}
+
+	return results, nil
+}
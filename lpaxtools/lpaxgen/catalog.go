@@ -0,0 +1,266 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxgen generates the Go source of an lpax language pack from a YAML or JSON catalog.
+//
+// A catalog lists the pack's messages and their text in each language:
+//
+//	package: messages
+//	pack: 1
+//	priority: package
+//	texts:
+//	  - name: Hello
+//	    doc: Hello greets the world.
+//	    single:
+//	      en: Hello World
+//	      fr: Bonjour le monde
+//	  - name: Files
+//	    id: 10
+//	    single:
+//	      en: "%d file"
+//	    plural:
+//	      en: "%d files"
+//	    categories:
+//	      pl:
+//	        few: "%d pliki"
+//
+// The generated source declares the PackID and TextID types, the TextID methods implemented using
+// lpax.IntTypeSingle and lpax.IntTypePlural, a constant for each message, a TextMap for each language
+// and an init function registering the pack with the lpax Default registry.
+package lpaxgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Catalog describes a language pack.
+	Catalog struct {
+		// Package is the name of the generated package.
+		Package string `json:"package" yaml:"package"`
+
+		// Pack is the value of the pack's PackID, defaulting to 1.
+		Pack int `json:"pack" yaml:"pack"`
+
+		// PackIDType and TextIDType are the names of the generated id types, defaulting to PackID and TextID.
+		PackIDType string `json:"packIDType" yaml:"packIDType"`
+		TextIDType string `json:"textIDType" yaml:"textIDType"`
+
+		// Priority is the registration priority, additional, package or override, defaulting to package.
+		Priority string `json:"priority" yaml:"priority"`
+
+		// Texts are the messages of the pack.
+		Texts []Text `json:"texts" yaml:"texts"`
+	}
+
+	// Text is a message of the catalog.
+	Text struct {
+		// Name is the name of the message's TextID constant.
+		Name string `json:"name" yaml:"name"`
+
+		// ID is the message's id, zero to use the id following the previous message's id.
+		ID int `json:"id" yaml:"id"`
+
+		// Doc is the doc comment of the constant, defaulting to the default language's single text.
+		Doc string `json:"doc" yaml:"doc"`
+
+		// Single and Plural are the single and plural texts keyed by BCP 47 language.
+		Single map[string]string `json:"single" yaml:"single"`
+		Plural map[string]string `json:"plural" yaml:"plural"`
+
+		// Categories are CLDR plural category variants keyed by language and then category name.
+		Categories map[string]map[string]string `json:"categories" yaml:"categories"`
+	}
+)
+
+// ReadCatalog reads a catalog, name's extension selects JSON (.json) or YAML decoding.
+func ReadCatalog(r io.Reader, name string) (*Catalog, error) {
+	var c Catalog
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return nil, err
+		}
+
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported catalog format %q", filepath.Ext(name))
+	}
+
+	return &c, nil
+}
+
+// setDefaults sets the defaults of the catalog's optional settings.
+func (c *Catalog) setDefaults() {
+	if c.Pack == 0 {
+		c.Pack = 1
+	}
+	if c.PackIDType == "" {
+		c.PackIDType = "PackID"
+	}
+	if c.TextIDType == "" {
+		c.TextIDType = "TextID"
+	}
+	if c.Priority == "" {
+		c.Priority = "package"
+	}
+}
+
+// priorities maps the catalog priorities to the lpax constants.
+var priorities = map[string]string{
+	"additional": "AdditionalPacks",
+	"package":    "Package",
+	"override":   "Override",
+}
+
+// categories maps the CLDR category names to the lpax constants.
+var categories = map[string]string{
+	"zero":  "PluralZero",
+	"one":   "PluralOne",
+	"two":   "PluralTwo",
+	"few":   "PluralFew",
+	"many":  "PluralMany",
+	"other": "PluralOther",
+}
+
+// validate checks the catalog and assigns the message ids, returning the languages used with
+// the lpax.DefaultLanguage first.
+func (c *Catalog) validate() ([]language.Tag, error) {
+	if !token.IsIdentifier(c.Package) {
+		return nil, fmt.Errorf("invalid package name %q", c.Package)
+	}
+
+	for _, name := range []string{c.PackIDType, c.TextIDType} {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("invalid type name %q", name)
+		}
+	}
+
+	if _, ok := priorities[c.Priority]; !ok {
+		return nil, fmt.Errorf("unknown priority %q", c.Priority)
+	}
+
+	if len(c.Texts) == 0 {
+		return nil, errors.New("catalog has no texts")
+	}
+
+	names := make(map[string]bool)
+	tags := make(map[language.Tag]bool)
+	id := 0
+
+	for i := range c.Texts {
+		t := &c.Texts[i]
+
+		if !token.IsExported(t.Name) || !token.IsIdentifier(t.Name) || names[t.Name] {
+			return nil, fmt.Errorf("invalid or duplicate text name %q", t.Name)
+		}
+		names[t.Name] = true
+
+		switch {
+		case t.ID == 0:
+			id++
+			t.ID = id
+		case t.ID <= id:
+			return nil, fmt.Errorf("text %s id %d must be greater than %d", t.Name, t.ID, id)
+		default:
+			id = t.ID
+		}
+
+		if err := t.addTags(tags); err != nil {
+			return nil, fmt.Errorf("text %s: %w", t.Name, err)
+		}
+	}
+
+	return sortTags(tags), nil
+}
+
+// addTags adds the languages of the text to tags.
+func (t *Text) addTags(tags map[language.Tag]bool) error {
+	langs := make([]string, 0, len(t.Single)+len(t.Plural)+len(t.Categories))
+	for lang := range t.Single {
+		langs = append(langs, lang)
+	}
+	for lang := range t.Plural {
+		langs = append(langs, lang)
+	}
+	for lang, variants := range t.Categories {
+		langs = append(langs, lang)
+
+		for name := range variants {
+			if _, ok := categories[name]; !ok {
+				return fmt.Errorf("unknown plural category %q", name)
+			}
+		}
+	}
+
+	if len(langs) == 0 {
+		return errors.New("no text")
+	}
+
+	for _, lang := range langs {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return err
+		}
+
+		if tag.String() != lang {
+			return fmt.Errorf("language %q must be written as %q", lang, tag)
+		}
+
+		tags[tag] = true
+	}
+
+	return nil
+}
+
+// sortTags returns the tags ordered with the lpax.DefaultLanguage first.
+func sortTags(tags map[language.Tag]bool) []language.Tag {
+	defaultTag := language.MustParse(lpax.DefaultLanguage)
+
+	sorted := make([]language.Tag, 0, len(tags))
+	for tag := range tags {
+		sorted = append(sorted, tag)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if (sorted[i] == defaultTag) != (sorted[j] == defaultTag) {
+			return sorted[i] == defaultTag
+		}
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	return sorted
+}
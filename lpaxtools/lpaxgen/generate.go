@@ -0,0 +1,233 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+type (
+	// templateData is the data passed to the source template.
+	templateData struct {
+		*Catalog
+		Source    string
+		PackConst string
+		Priority  string
+		Languages []templateLanguage
+	}
+
+	// templateLanguage is the text of a language.
+	templateLanguage struct {
+		Tag     string
+		VarName string
+		Entries []templateEntry
+	}
+
+	// templateEntry is a TextMap entry.
+	templateEntry struct {
+		Key  string
+		Text string
+	}
+)
+
+var sourceTemplate = template.Must(template.New("source").Parse(`// Code generated by lpaxgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type (
+	// {{.PackIDType}} is the id type of the package's language pack.
+	{{.PackIDType}} int
+
+	// {{.TextIDType}} is the id type of the package's messages.
+	{{.TextIDType}} int
+)
+
+// Single returns the id of the single version of a message.
+func (id {{.TextIDType}}) Single() lpax.TextID {
+	return {{.TextIDType}}(lpax.IntTypeSingle(int(id)))
+}
+
+// Plural returns the id of the plural version of a message.
+func (id {{.TextIDType}}) Plural() lpax.TextID {
+	return {{.TextIDType}}(lpax.IntTypePlural(int(id)))
+}
+
+// String returns the package name followed by the id of the single version of the message, e.g. {{.Package}}-00001.
+func (id {{.TextIDType}}) String() string {
+	return lpax.ReflectCoderString(id.Single())
+}
+
+// {{.PackConst}} is the id of the package's language pack.
+const {{.PackConst}} = {{.PackIDType}}({{.Pack}})
+
+const (
+{{- range .Texts}}
+	// {{.Doc}}
+	{{.Name}} = {{$.TextIDType}}({{.ID}})
+{{end -}}
+)
+
+var (
+{{- range .Languages}}
+	// {{.VarName}} are the {{.Tag}} texts of the pack.
+	{{.VarName}} = lpax.TextMap{
+	{{- range .Entries}}
+		{{.Key}}: {{.Text}},
+	{{- end}}
+	}
+{{end -}}
+)
+
+// onRegister returns the texts of the pack in the language langTag.
+func onRegister(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+	switch langTag.String() {
+{{- range .Languages}}
+	case "{{.Tag}}":
+		return {{.VarName}}
+{{- end}}
+	}
+	return nil
+}
+
+func init() {
+	lpax.Default().Register({{.PackConst}}, onRegister, lpax.{{.Priority}},
+	{{- range .Languages}}
+		language.MustParse("{{.Tag}}"),
+	{{- end}}
+	)
+}
+`))
+
+// Generate writes the formatted Go source of the catalog's language pack.  source names the
+// catalog in the generated file's header.
+func Generate(w io.Writer, c *Catalog, source string) error {
+	c.setDefaults()
+
+	tags, err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	data := &templateData{
+		Catalog:   c,
+		Source:    source,
+		PackConst: exportedName(c.Package) + c.PackIDType,
+		Priority:  priorities[c.Priority],
+	}
+
+	for i := range c.Texts {
+		t := &c.Texts[i]
+		t.Doc = docComment(t, tags[0])
+	}
+
+	for _, tag := range tags {
+		data.Languages = append(data.Languages, c.language(tag))
+	}
+
+	var buf bytes.Buffer
+	if err := sourceTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated source: %w", err)
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+// language returns the texts of a language.
+func (c *Catalog) language(tag language.Tag) templateLanguage {
+	lang := tag.String()
+	tl := templateLanguage{Tag: lang, VarName: "texts" + varSuffix(lang)}
+
+	for _, t := range c.Texts {
+		if s, ok := t.Single[lang]; ok {
+			tl.Entries = append(tl.Entries, templateEntry{Key: t.Name, Text: strconv.Quote(s)})
+		}
+
+		if s, ok := t.Plural[lang]; ok {
+			tl.Entries = append(tl.Entries, templateEntry{Key: "-" + t.Name, Text: strconv.Quote(s)})
+		}
+
+		variants := t.Categories[lang]
+		names := make([]string, 0, len(variants))
+		for name := range variants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			tl.Entries = append(tl.Entries, templateEntry{
+				Key:  fmt.Sprintf("lpax.ByCategory(%s, lpax.%s)", t.Name, categories[name]),
+				Text: strconv.Quote(variants[name]),
+			})
+		}
+	}
+
+	return tl
+}
+
+// docComment returns the doc comment of the text's constant.
+func docComment(t *Text, defaultTag language.Tag) string {
+	doc := t.Doc
+	if doc == "" {
+		doc = t.Name + " is the message " + strconv.Quote(t.Single[defaultTag.String()]) + "."
+		if _, ok := t.Single[defaultTag.String()]; !ok {
+			doc = t.Name + " is a message of the pack."
+		}
+	}
+
+	// continue multiple line comments
+	return strings.ReplaceAll(strings.TrimSpace(doc), "\n", "\n\t// ")
+}
+
+// varSuffix converts a BCP 47 language to a variable name suffix, e.g. pt-BR to PtBR.
+func varSuffix(lang string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(lang, "-") {
+		b.WriteString(exportedName(part))
+	}
+	return b.String()
+}
+
+// exportedName returns name with its first letter in upper case.
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxgen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readTestCatalog(t *testing.T, name string) *Catalog {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c, err := ReadCatalog(f, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// TestGenerateExample checks the committed example package is up to date with the generator.
+func TestGenerateExample(t *testing.T) {
+	c := readTestCatalog(t, "internal/example/messages.yaml")
+
+	var buf bytes.Buffer
+	if err := Generate(&buf, c, "messages.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ioutil.ReadFile("internal/example/messages_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != string(want) {
+		t.Errorf("generated source differs, run go generate ./...\n%s", buf.String())
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	catalog := `{
+		"package": "errs",
+		"pack": 7,
+		"packIDType": "ErrPack",
+		"textIDType": "Err",
+		"priority": "override",
+		"texts": [{"name": "NotFound", "single": {"en": "not found", "pt-BR": "não encontrado"}}]
+	}`
+
+	c, err := ReadCatalog(strings.NewReader(catalog), "errs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Generate(&buf, c, "errs.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := buf.String()
+	for _, want := range []string{
+		"package errs",
+		"ErrPack int",
+		"func (id Err) Single() lpax.TextID",
+		"const ErrsErrPack = ErrPack(7)",
+		`// NotFound is the message "not found".`,
+		"NotFound = Err(1)",
+		"textsPtBR = lpax.TextMap{",
+		`case "pt-BR":`,
+		"lpax.Default().Register(ErrsErrPack, onRegister, lpax.Override,",
+	} {
+		if !strings.Contains(src, want) {
+			t.Error("missing", want)
+		}
+	}
+}
+
+func TestReadCatalogErrors(t *testing.T) {
+	for name, catalog := range map[string]string{
+		"c.txt":  `{}`,
+		"c.json": `{"unknown": 1}`,
+		"c.yaml": "unknown: 1",
+	} {
+		if _, err := ReadCatalog(strings.NewReader(catalog), name); err == nil {
+			t.Error("no error", name)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for name, catalog := range map[string]string{
+		"package":       `{"package": "1x", "texts": [{"name": "A", "single": {"en": "a"}}]}`,
+		"type":          `{"package": "p", "textIDType": "a b", "texts": [{"name": "A", "single": {"en": "a"}}]}`,
+		"priority":      `{"package": "p", "priority": "high", "texts": [{"name": "A", "single": {"en": "a"}}]}`,
+		"no texts":      `{"package": "p"}`,
+		"unexported":    `{"package": "p", "texts": [{"name": "a", "single": {"en": "a"}}]}`,
+		"duplicate":     `{"package": "p", "texts": [{"name": "A", "single": {"en": "a"}}, {"name": "A", "single": {"en": "a"}}]}`,
+		"id order":      `{"package": "p", "texts": [{"name": "A", "id": 5, "single": {"en": "a"}}, {"name": "B", "id": 5, "single": {"en": "b"}}]}`,
+		"no text":       `{"package": "p", "texts": [{"name": "A"}]}`,
+		"bad language":  `{"package": "p", "texts": [{"name": "A", "single": {"xx-!!": "a"}}]}`,
+		"non canonical": `{"package": "p", "texts": [{"name": "A", "single": {"EN": "a"}}]}`,
+		"category":      `{"package": "p", "texts": [{"name": "A", "categories": {"en": {"lots": "a"}}}]}`,
+	} {
+		c, err := ReadCatalog(strings.NewReader(catalog), "c.json")
+		if err != nil {
+			t.Fatal(name, err)
+		}
+
+		if err := Generate(&bytes.Buffer{}, c, "c.json"); err == nil {
+			t.Error("no error", name)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package example
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+func TestGeneratedPack(t *testing.T) {
+	if s := lpax.Default().New(language.English).Text(Hello); s != "Hello World" {
+		t.Error("english", s)
+	}
+
+	if s := lpax.Default().New(language.French).Text(Hello); s != "Bonjour le monde" {
+		t.Error("french", s)
+	}
+
+	polish := lpax.WithContext(context.Background(), lpax.NewFinder(lpax.Default(), language.Polish))
+
+	if s := lpax.CtxSprintfCount(polish, Files, 3, 3); s != "3 pliki" {
+		t.Error("polish few", s)
+	}
+
+	if s := lpax.CtxSprintfCount(polish, Files, 5, 5); s != "5 plików" {
+		t.Error("polish many", s)
+	}
+
+	if code := lpax.TextCode(Files.Plural()); code != "example-00010" {
+		t.Error("code", code)
+	}
+}
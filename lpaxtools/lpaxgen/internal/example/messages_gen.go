@@ -0,0 +1,88 @@
+// Code generated by lpaxgen from messages.yaml. DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type (
+	// PackID is the id type of the package's language pack.
+	PackID int
+
+	// TextID is the id type of the package's messages.
+	TextID int
+)
+
+// Single returns the id of the single version of a message.
+func (id TextID) Single() lpax.TextID {
+	return TextID(lpax.IntTypeSingle(int(id)))
+}
+
+// Plural returns the id of the plural version of a message.
+func (id TextID) Plural() lpax.TextID {
+	return TextID(lpax.IntTypePlural(int(id)))
+}
+
+// String returns the package name followed by the id of the single version of the message, e.g. example-00001.
+func (id TextID) String() string {
+	return lpax.ReflectCoderString(id.Single())
+}
+
+// ExamplePackID is the id of the package's language pack.
+const ExamplePackID = PackID(1)
+
+const (
+	// Hello greets the world.
+	Hello = TextID(1)
+
+	// Files is the message "%d file".
+	Files = TextID(10)
+
+	// Quoted is the message "Say \"%s\"\n".
+	Quoted = TextID(11)
+)
+
+var (
+	// textsEn are the en texts of the pack.
+	textsEn = lpax.TextMap{
+		Hello:  "Hello World",
+		Files:  "%d file",
+		-Files: "%d files",
+		Quoted: "Say \"%s\"\n",
+	}
+
+	// textsFr are the fr texts of the pack.
+	textsFr = lpax.TextMap{
+		Hello: "Bonjour le monde",
+	}
+
+	// textsPl are the pl texts of the pack.
+	textsPl = lpax.TextMap{
+		Files:                                  "%d plik",
+		-Files:                                 "%d plików",
+		lpax.ByCategory(Files, lpax.PluralFew): "%d pliki",
+	}
+)
+
+// onRegister returns the texts of the pack in the language langTag.
+func onRegister(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+	switch langTag.String() {
+	case "en":
+		return textsEn
+	case "fr":
+		return textsFr
+	case "pl":
+		return textsPl
+	}
+	return nil
+}
+
+func init() {
+	lpax.Default().Register(ExamplePackID, onRegister, lpax.Package,
+		language.MustParse("en"),
+		language.MustParse("fr"),
+		language.MustParse("pl"),
+	)
+}
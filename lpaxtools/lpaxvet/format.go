@@ -0,0 +1,44 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxvet
+
+import "github.com/nehemming/lpax/internal/printf"
+
+// formatArgs returns the number of args a fmt format string uses and if it uses explicit
+// argument indexes, in which case fmt does not complain about extra args.  The format is parsed
+// by the parser lpax uses to validate translations so both agree on the args a format needs.
+func formatArgs(format string) (need int, reordered bool) {
+	use := func(arg int) {
+		if arg+1 > need {
+			need = arg + 1
+		}
+	}
+
+	for _, v := range printf.Parse(format) {
+		reordered = reordered || v.Indexed
+
+		for _, arg := range v.StarArgs {
+			use(arg)
+		}
+
+		if v.Verb != 0 && v.Verb != '%' {
+			use(v.Arg)
+		}
+	}
+
+	return need, reordered
+}
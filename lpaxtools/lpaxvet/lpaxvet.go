@@ -0,0 +1,537 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxvet defines an Analyzer that reports misuse of the lpax package.
+//
+// The analyzer reports:
+//
+//   - TextID types, and the PackIDs passed to Register, whose kind is not an integer, string or struct.
+//     lpax panics at run time when such ids are registered or used as TextMap keys.
+//   - calls to Sprintf, Errorf and the other printf style functions whose number of args disagrees with
+//     the text registered for the id in the lpax.DefaultLanguage.
+//   - options passed to a registry's New, NewFinder or NewLiveFinder that are not a Tag, string, TextMap,
+//     []TextMap or MissingPolicy.
+//   - the Override priority used outside package main.
+//
+// The default language text is found in the TextMap literals returned for the default language by the
+// callbacks passed to Register, either directly or from a switch on the language.  Text loaded at run time,
+// for example from JSON packs, is not checked.
+package lpaxvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"github.com/nehemming/lpax/lpaxtools/internal/textid"
+	"golang.org/x/text/language"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// languagePath is the import path of the x/text language package.
+const languagePath = "golang.org/x/text/language"
+
+// Analyzer reports misuse of the lpax package.
+var Analyzer = &analysis.Analyzer{
+	Name:      "lpaxvet",
+	Doc:       "report misuse of the lpax package\n\nlpaxvet checks TextID and PackID types, the args of lpax printf style calls, registry New options and the use of the Override priority.",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(formatFact)},
+}
+
+// formatFact is the default language text of a TextID constant, exported so calls in other packages can
+// be checked.
+type formatFact struct {
+	Single    string
+	Plural    string
+	HasSingle bool
+	HasPlural bool
+}
+
+// AFact marks formatFact as an analysis.Fact.
+func (*formatFact) AFact() {}
+
+func (f *formatFact) String() string {
+	return fmt.Sprintf("format(%q, %q)", f.Single, f.Plural)
+}
+
+// printfFuncs are the lpax functions passing their trailing args to a format.
+var printfFuncs = map[string]bool{
+	"Sprintf":           true,
+	"CtxSprintf":        true,
+	"Errorf":            true,
+	"CtxErrorf":         true,
+	"NewError":          true,
+	"SprintfCount":      true,
+	"CtxSprintfCount":   true,
+	"SprintfOrdinal":    true,
+	"CtxSprintfOrdinal": true,
+}
+
+// languageVars are the x/text language variables recognized as registration languages.
+var languageVars = map[string]language.Tag{
+	"English":         language.English,
+	"AmericanEnglish": language.AmericanEnglish,
+	"BritishEnglish":  language.BritishEnglish,
+}
+
+// defaultTag is the language whose text is checked.
+var defaultTag = language.MustParse(lpax.DefaultLanguage)
+
+type checker struct {
+	pass     *analysis.Pass
+	lpax     *types.Package
+	textID   types.Type
+	override types.Object
+	formats  map[*types.Const]*formatFact
+	decls    map[types.Object]ast.Node // package level func declarations and var initializers
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	lpaxPkg := importedPackage(pass.Pkg, textid.LpaxPath)
+	if lpaxPkg == nil {
+		return nil, nil
+	}
+
+	c := &checker{
+		pass:     pass,
+		lpax:     lpaxPkg,
+		textID:   lpaxPkg.Scope().Lookup("TextID").Type(),
+		override: lpaxPkg.Scope().Lookup("Override"),
+		formats:  make(map[*types.Const]*formatFact),
+	}
+
+	c.checkTypes()
+
+	// register calls are checked first so the text they register is known when the printf calls are checked
+	var printfCalls []*ast.CallExpr
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil), (*ast.Ident)(nil)}, func(n ast.Node) {
+		if ident, ok := n.(*ast.Ident); ok {
+			c.checkOverride(ident)
+			return
+		}
+
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() != lpaxPkg {
+			return
+		}
+
+		method := fn.Type().(*types.Signature).Recv() != nil
+		switch {
+		case method && fn.Name() == "Register":
+			c.checkRegister(call)
+		case method && (fn.Name() == "New" || fn.Name() == "NewFinder"), !method && (fn.Name() == "NewFinder" || fn.Name() == "NewLiveFinder"):
+			c.checkOptions(fn, call)
+		case !method && printfFuncs[fn.Name()]:
+			printfCalls = append(printfCalls, call)
+		}
+	})
+
+	for obj, fact := range c.formats {
+		if obj.Pkg() == pass.Pkg {
+			pass.ExportObjectFact(obj, fact)
+		}
+	}
+
+	for _, call := range printfCalls {
+		c.checkPrintf(call)
+	}
+
+	return nil, nil
+}
+
+// importedPackage returns the package with the import path imported by pkg, or nil.
+func importedPackage(pkg *types.Package, path string) *types.Package {
+	for _, imp := range pkg.Imports() {
+		if imp.Path() == path {
+			return imp
+		}
+	}
+	return nil
+}
+
+// validKind reports if a type may be used as a TextID or PackID.
+func validKind(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return (u.Info()&types.IsInteger != 0 && u.Kind() != types.Uintptr && u.Kind() != types.UnsafePointer) ||
+			u.Info()&types.IsString != 0
+	case *types.Struct:
+		return true
+	}
+	return false
+}
+
+// checkTypes reports the TextID types declared by the package with an invalid kind.
+func (c *checker) checkTypes() {
+	iface := c.textID.Underlying().(*types.Interface)
+
+	for ident, obj := range c.pass.TypesInfo.Defs {
+		tn, ok := obj.(*types.TypeName)
+		if !ok || tn.IsAlias() || types.IsInterface(tn.Type()) {
+			continue
+		}
+
+		switch t := tn.Type(); {
+		case types.Implements(t, iface):
+			if !validKind(t) {
+				c.pass.Reportf(ident.Pos(), "TextID type %s must be an integer, string or struct, not %s", tn.Name(), t.Underlying())
+			}
+		case types.Implements(types.NewPointer(t), iface):
+			c.pass.Reportf(ident.Pos(), "TextID type %s implements TextID with pointer receivers, ids must be integer, string or struct values", tn.Name())
+		}
+	}
+}
+
+// checkOverride reports uses of the Override priority outside package main.
+func (c *checker) checkOverride(ident *ast.Ident) {
+	if c.pass.Pkg.Name() != "main" && c.pass.TypesInfo.Uses[ident] == c.override {
+		c.pass.Reportf(ident.Pos(), "the Override priority should only be used by package main")
+	}
+}
+
+// checkOptions reports New options that are not a Tag, string, TextMap, []TextMap or MissingPolicy.
+func (c *checker) checkOptions(fn *types.Func, call *ast.CallExpr) {
+	if call.Ellipsis.IsValid() {
+		return
+	}
+
+	first := fn.Type().(*types.Signature).Params().Len() - 1
+	for _, arg := range call.Args[first:] {
+		t := c.pass.TypesInfo.TypeOf(arg)
+		if t == nil || types.IsInterface(t) || c.validOption(t) {
+			continue
+		}
+
+		c.pass.Reportf(arg.Pos(), "unsupported option %s of type %s, options must be a Tag, string, TextMap, []TextMap or MissingPolicy",
+			types.ExprString(arg), t)
+	}
+}
+
+// validOption reports if the type is supported as a New option.
+func (c *checker) validOption(t types.Type) bool {
+	if b, ok := t.(*types.Basic); ok {
+		return b.Kind() == types.String || b.Kind() == types.UntypedString
+	}
+
+	textMap := c.lpax.Scope().Lookup("TextMap").Type()
+	if types.Identical(t, textMap) || types.Identical(t, types.NewSlice(textMap)) {
+		return true
+	}
+
+	if policy := c.lpax.Scope().Lookup("MissingPolicy"); policy != nil && types.Identical(t, policy.Type()) {
+		return true
+	}
+
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == languagePath && named.Obj().Name() == "Tag"
+}
+
+// checkRegister checks the pack id of a Register call and records the text it registers for the default language.
+func (c *checker) checkRegister(call *ast.CallExpr) {
+	if len(call.Args) < 3 {
+		return
+	}
+
+	if t := c.pass.TypesInfo.TypeOf(call.Args[0]); t != nil && !types.IsInterface(t) && !validKind(t) {
+		c.pass.Reportf(call.Args[0].Pos(), "PackID %s must be an integer, string or struct, not %s", types.ExprString(call.Args[0]), t)
+	}
+
+	if call.Ellipsis.IsValid() || !c.anyDefaultTag(call.Args[3:]) {
+		return
+	}
+
+	body := c.funcBody(call.Args[1])
+	if body == nil {
+		return
+	}
+
+	for _, result := range c.defaultReturns(body) {
+		if lit := c.textMapLiteral(result); lit != nil {
+			c.recordFormats(lit)
+		}
+	}
+}
+
+// anyDefaultTag reports if one of the expressions is the default language.
+func (c *checker) anyDefaultTag(exprs []ast.Expr) bool {
+	for _, e := range exprs {
+		if c.isDefaultTag(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDefaultTag reports if an expression is a constant string, a x/text language variable or a call to
+// language.MustParse or language.Make with a constant naming the default language.
+func (c *checker) isDefaultTag(e ast.Expr) bool {
+	e = ast.Unparen(e)
+
+	if tv, ok := c.pass.TypesInfo.Types[e]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		tag, err := language.Parse(constant.StringVal(tv.Value))
+		return err == nil && tag == defaultTag
+	}
+
+	switch x := e.(type) {
+	case *ast.CallExpr:
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, x).(*types.Func)
+		if ok && fn.Pkg() != nil && fn.Pkg().Path() == languagePath && (fn.Name() == "MustParse" || fn.Name() == "Make") && len(x.Args) == 1 {
+			return c.isDefaultTag(x.Args[0])
+		}
+
+	case *ast.SelectorExpr:
+		v, ok := c.pass.TypesInfo.Uses[x.Sel].(*types.Var)
+		if ok && v.Pkg() != nil && v.Pkg().Path() == languagePath {
+			tag, found := languageVars[v.Name()]
+			return found && tag == defaultTag
+		}
+	}
+
+	return false
+}
+
+// funcBody returns the body of a function literal or a function declared by the package.
+func (c *checker) funcBody(e ast.Expr) *ast.BlockStmt {
+	switch x := ast.Unparen(e).(type) {
+	case *ast.FuncLit:
+		return x.Body
+	case *ast.Ident:
+		if decl, ok := c.declaration(c.pass.TypesInfo.Uses[x]).(*ast.FuncDecl); ok {
+			return decl.Body
+		}
+	}
+	return nil
+}
+
+// declaration returns the package level function declaration or var initializer of an object.
+func (c *checker) declaration(obj types.Object) ast.Node {
+	if obj == nil || obj.Pkg() != c.pass.Pkg {
+		return nil
+	}
+
+	if c.decls == nil {
+		c.decls = make(map[types.Object]ast.Node)
+
+		for _, file := range c.pass.Files {
+			for _, decl := range file.Decls {
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					c.decls[c.pass.TypesInfo.Defs[d.Name]] = d
+
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						vs, ok := spec.(*ast.ValueSpec)
+						if !ok || len(vs.Names) != len(vs.Values) {
+							continue
+						}
+						for i, name := range vs.Names {
+							c.decls[c.pass.TypesInfo.Defs[name]] = vs.Values[i]
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return c.decls[obj]
+}
+
+// defaultReturns returns the results of the return statements of a register callback reached for the
+// default language.  Only the clauses of switch statements matching the default language, or the default
+// clause if none do, are followed.
+func (c *checker) defaultReturns(body *ast.BlockStmt) []ast.Expr {
+	var results []ast.Expr
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return false
+
+		case *ast.ReturnStmt:
+			if len(x.Results) == 1 {
+				results = append(results, x.Results[0])
+			}
+
+		case *ast.SwitchStmt:
+			if x.Init != nil {
+				ast.Inspect(x.Init, visit)
+			}
+			for _, clause := range c.defaultClauses(x) {
+				for _, stmt := range clause.Body {
+					ast.Inspect(stmt, visit)
+				}
+			}
+			return false
+		}
+		return true
+	}
+
+	ast.Inspect(body, visit)
+	return results
+}
+
+// defaultClauses returns the switch clauses reached for the default language.
+func (c *checker) defaultClauses(sw *ast.SwitchStmt) []*ast.CaseClause {
+	var matched, all []*ast.CaseClause
+	var defaultClause *ast.CaseClause
+
+	for _, stmt := range sw.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		all = append(all, clause)
+
+		if clause.List == nil {
+			defaultClause = clause
+		} else if sw.Tag != nil && c.anyDefaultTag(clause.List) {
+			matched = append(matched, clause)
+		}
+	}
+
+	switch {
+	case sw.Tag == nil:
+		// conditions cannot be evaluated
+		return all
+	case len(matched) > 0:
+		return matched
+	case defaultClause != nil:
+		return []*ast.CaseClause{defaultClause}
+	}
+	return nil
+}
+
+// textMapLiteral returns the TextMap composite literal an expression is, or a package variable is initialized with.
+func (c *checker) textMapLiteral(e ast.Expr) *ast.CompositeLit {
+	switch x := ast.Unparen(e).(type) {
+	case *ast.CompositeLit:
+		if types.Identical(c.pass.TypesInfo.TypeOf(x), c.lpax.Scope().Lookup("TextMap").Type()) {
+			return x
+		}
+
+	case *ast.Ident:
+		if init, ok := c.declaration(c.pass.TypesInfo.Uses[x]).(ast.Expr); ok {
+			return c.textMapLiteral(init)
+		}
+	}
+	return nil
+}
+
+// recordFormats records the constant texts of a TextMap literal.
+func (c *checker) recordFormats(lit *ast.CompositeLit) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+
+		tv := c.pass.TypesInfo.Types[kv.Value]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			continue
+		}
+
+		id, plural := textid.ResolveConst(c.pass.TypesInfo, kv.Key)
+		if id == nil {
+			continue
+		}
+
+		fact := c.formats[id]
+		if fact == nil {
+			fact = &formatFact{}
+			c.formats[id] = fact
+		}
+
+		if plural {
+			fact.Plural, fact.HasPlural = constant.StringVal(tv.Value), true
+		} else {
+			fact.Single, fact.HasSingle = constant.StringVal(tv.Value), true
+		}
+	}
+}
+
+// format returns the default language text of a constant.
+func (c *checker) format(id *types.Const) *formatFact {
+	if fact := c.formats[id]; fact != nil {
+		return fact
+	}
+
+	fact := new(formatFact)
+	if id.Pkg() != c.pass.Pkg && c.pass.ImportObjectFact(id, fact) {
+		return fact
+	}
+	return nil
+}
+
+// checkPrintf reports a printf style call whose number of args disagrees with the default language text.
+func (c *checker) checkPrintf(call *ast.CallExpr) {
+	if call.Ellipsis.IsValid() {
+		return
+	}
+
+	fn := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+	params := fn.Type().(*types.Signature).Params()
+
+	var idArg ast.Expr
+	for i := 0; i < params.Len()-1 && i < len(call.Args); i++ {
+		if types.Identical(params.At(i).Type(), c.textID) {
+			idArg = call.Args[i]
+		}
+	}
+
+	if idArg == nil || len(call.Args) < params.Len()-1 {
+		return
+	}
+
+	id, plural := textid.ResolveConst(c.pass.TypesInfo, idArg)
+	if id == nil {
+		return
+	}
+
+	fact := c.format(id)
+	if fact == nil {
+		return
+	}
+
+	// the variant used by the count and ordinal functions is chosen at run time so both are checked
+	variants := strings.Contains(fn.Name(), "Count") || strings.Contains(fn.Name(), "Ordinal")
+
+	var formats []string
+	if (plural || variants) && fact.HasPlural {
+		formats = append(formats, fact.Plural)
+	}
+	if (!plural || variants) && fact.HasSingle {
+		formats = append(formats, fact.Single)
+	}
+
+	n := len(call.Args) - (params.Len() - 1)
+	for _, f := range formats {
+		need, reordered := formatArgs(f)
+		if n < need || (n > need && !reordered) {
+			c.pass.Reportf(call.Pos(), "%s call has %d args but the %s text %q of %s.%s needs %d",
+				fn.Name(), n, lpax.DefaultLanguage, f, id.Pkg().Name(), id.Name(), need)
+			return
+		}
+	}
+}
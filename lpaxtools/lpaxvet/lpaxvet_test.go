@@ -0,0 +1,54 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxvet
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "./...")
+}
+
+func TestFormatArgs(t *testing.T) {
+	for _, tc := range []struct {
+		format    string
+		need      int
+		reordered bool
+	}{
+		{"", 0, false},
+		{"100%%", 0, false},
+		{"%s and %v", 2, false},
+		{"%-8.2f|%+d", 2, false},
+		{"%*d", 2, false},
+		{"%.*f", 2, false},
+		{"%[2]v %[1]v", 2, true},
+		{"%[3]*.[2]*[1]f", 3, true},
+		{"%d%", 1, false},
+		{"%é", 1, false},
+		{"%[2]d %d", 3, true},
+		{"%-+# 05d", 1, false},
+		{"%[x]d", 1, true},
+	} {
+		need, reordered := formatArgs(tc.format)
+		if need != tc.need || reordered != tc.reordered {
+			t.Errorf("%q got %d %v", tc.format, need, reordered)
+		}
+	}
+}
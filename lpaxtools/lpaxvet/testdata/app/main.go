@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/vet/msgs"
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+func main() {
+	ctx := context.Background()
+	args := []interface{}{"world"}
+
+	fmt.Println(lpax.Sprintf(msgs.Hello, "world", 2)) // want `Sprintf call has 2 args but the en text "Hello %s" of msgs.Hello needs 1`
+	fmt.Println(lpax.Sprintf(msgs.Hello, args...))
+	fmt.Println(lpax.Errorf(-msgs.Files, 2, "dir"))
+	fmt.Println(lpax.Errorf(msgs.Files.Plural(), 2))         // want `Errorf call has 1 args but the en text "%d files in %s" of msgs.Files needs 2`
+	fmt.Println(lpax.CtxSprintfCount(ctx, msgs.Files, 2, 2)) // want `CtxSprintfCount call has 1 args but the en text "%d files in %s" of msgs.Files needs 2`
+	fmt.Println(lpax.Sprintf(msgs.Ordered, 1, 2, 3))
+	fmt.Println(lpax.Sprintf(msgs.Ordered, 1)) // want `needs 2`
+
+	lpax.Default().New("en", language.French, lpax.TextMap{}, []lpax.TextMap{}, lpax.MissingPolicy{})
+	lpax.Default().New(language.English, 42)           // want `unsupported option 42 of type int`
+	lpax.NewFinder(lpax.Default(), "en", 1.5)          // want `unsupported option 1.5 of type float64`
+	lpax.NewLiveFinder(lpax.Default(), []string{"en"}) // want `unsupported option .* of type \[\]string`
+
+	lpax.Default().Register(msgs.PackID(2), func(lpax.PackID, lpax.Tag) lpax.TextMap { return nil }, lpax.Override, language.English)
+}
@@ -0,0 +1,18 @@
+module example.com/vet
+
+go 1.25.0
+
+require (
+	github.com/nehemming/lpax v0.0.0
+	golang.org/x/text v0.3.6
+)
+
+require (
+	github.com/cloudfoundry-attic/jibber_jabber v0.0.0-20151120183258-bcc4c8345a21 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781 // indirect
+	golang.org/x/sys v0.0.0-20210423082822-04245dca01da // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace github.com/nehemming/lpax => ../../../
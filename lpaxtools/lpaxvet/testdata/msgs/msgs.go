@@ -0,0 +1,71 @@
+package msgs
+
+import (
+	"fmt"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type (
+	PackID int
+
+	TextID int
+
+	floatID float64 // want `TextID type floatID must be an integer, string or struct, not float64`
+
+	pointerID struct{ n int } // want `TextID type pointerID implements TextID with pointer receivers`
+)
+
+func (id TextID) Single() lpax.TextID { return TextID(lpax.IntTypeSingle(int(id))) }
+func (id TextID) Plural() lpax.TextID { return TextID(lpax.IntTypePlural(int(id))) }
+func (id TextID) String() string      { return fmt.Sprint(int(id)) }
+
+func (id floatID) Single() lpax.TextID { return id }
+func (id floatID) Plural() lpax.TextID { return id }
+func (id floatID) String() string      { return "" }
+
+func (id *pointerID) Single() lpax.TextID { return id }
+func (id *pointerID) Plural() lpax.TextID { return id }
+func (id *pointerID) String() string      { return "" }
+
+const (
+	Hello   = TextID(1) // want Hello:`format\("Hello %s", ""\)`
+	Files   = TextID(2) // want Files:`format\("%d file", "%d files in %s"\)`
+	Ordered = TextID(3) // want Ordered:`format`
+)
+
+var english = lpax.TextMap{
+	Hello:   "Hello %s",
+	Files:   "%d file",
+	-Files:  "%d files in %s",
+	Ordered: "%[2]v %[1]v",
+}
+
+var french = lpax.TextMap{
+	Hello: "Bonjour",
+}
+
+func onRegister(packID lpax.PackID, langTag lpax.Tag) lpax.TextMap {
+	switch langTag.String() {
+	case "en":
+		return english
+	case "fr":
+		return french
+	}
+	return nil
+}
+
+func init() {
+	lpax.Default().Register(PackID(1), onRegister, lpax.Package, language.English, language.MustParse("fr"))
+
+	lpax.Default().Register(1.5, onRegister, lpax.Override, language.French) // want `PackID 1.5 must be an integer, string or struct, not float64` `the Override priority should only be used by package main`
+}
+
+func Greet(name string) string {
+	return lpax.Sprintf(Hello, name)
+}
+
+func Bad() string {
+	return lpax.Sprintf(Hello) // want `Sprintf call has 0 args but the en text "Hello %s" of msgs.Hello needs 1`
+}
@@ -0,0 +1,206 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxxliff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+// Result holds the translations read from an XLIFF document.
+type Result struct {
+	// Version is the XLIFF version of the document.
+	Version Version
+
+	// Language is the target language of the translations.
+	Language lpax.Tag
+
+	// Texts are the translated texts, including those needing review.
+	Texts lpax.TextMap
+
+	// Untranslated lists the ids of units without a translation.
+	Untranslated []string
+
+	// NeedsReview lists the ids of units whose translation is marked as needing review.
+	NeedsReview []string
+}
+
+type (
+	readXliff struct {
+		XMLName xml.Name
+		Version string     `xml:"version,attr"`
+		TrgLang string     `xml:"trgLang,attr"`
+		Files   []readFile `xml:"file"`
+	}
+
+	readFile struct {
+		TargetLanguage string        `xml:"target-language,attr"`
+		TransUnits     []transUnit12 `xml:"body>trans-unit"`
+		GroupUnits     []transUnit12 `xml:"body>group>trans-unit"`
+		Units          []readUnit20  `xml:"unit"`
+		Groups         []readGroup20 `xml:"group"`
+	}
+
+	readGroup20 struct {
+		Units  []readUnit20  `xml:"unit"`
+		Groups []readGroup20 `xml:"group"`
+	}
+
+	readUnit20 struct {
+		ID       string          `xml:"id,attr"`
+		Segments []readSegment20 `xml:"segment"`
+	}
+
+	readSegment20 struct {
+		State    string  `xml:"state,attr"`
+		SubState string  `xml:"subState,attr"`
+		Target   *string `xml:"target"`
+	}
+)
+
+// Read reads the translations for the language langTag from an XLIFF 1.2 or 2.0 document.
+// Unit ids are decoded with DecodeUnitID.  An error is returned if the document's target
+// language does not match langTag.
+//
+// Units with a missing or empty target are reported as untranslated.  XLIFF 1.2 targets with
+// a needs-review state and XLIFF 2.0 segments left in the initial state or with a needs-review
+// sub-state are reported as needing review.
+func Read(r io.Reader, langTag lpax.Tag, decode lpax.TextIDDecoder) (*Result, error) {
+	var doc readXliff
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Language: langTag, Texts: make(lpax.TextMap)}
+
+	switch doc.XMLName.Space {
+	case namespace12:
+		res.Version = Version12
+	case namespace20:
+		res.Version = Version20
+		if err := checkLanguage(doc.TrgLang, langTag); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported XLIFF namespace %q", doc.XMLName.Space)
+	}
+
+	for _, f := range doc.Files {
+		var err error
+		if res.Version == Version12 {
+			err = res.readFile12(f, decode)
+		} else {
+			err = res.readUnits20(f.Units, f.Groups, decode)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// checkLanguage checks the document language is langTag.
+func checkLanguage(lang string, langTag lpax.Tag) error {
+	if lang == "" {
+		return nil
+	}
+
+	docTag, err := language.Parse(lang)
+	if err != nil {
+		return err
+	}
+
+	if docTag != langTag {
+		return fmt.Errorf("target language %s does not match %s", docTag, langTag)
+	}
+
+	return nil
+}
+
+func (res *Result) readFile12(f readFile, decode lpax.TextIDDecoder) error {
+	if err := checkLanguage(f.TargetLanguage, res.Language); err != nil {
+		return err
+	}
+
+	for _, tu := range append(f.TransUnits, f.GroupUnits...) {
+		text, state := "", ""
+		if tu.Target != nil {
+			text, state = tu.Target.Text, tu.Target.State
+		}
+
+		if err := res.add(tu.ID, text, strings.HasPrefix(state, "needs-review"), decode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (res *Result) readUnits20(units []readUnit20, groups []readGroup20, decode lpax.TextIDDecoder) error {
+	for _, u := range units {
+		var text strings.Builder
+		review := false
+
+		for _, s := range u.Segments {
+			if s.Target == nil {
+				continue
+			}
+
+			text.WriteString(*s.Target)
+			review = review || s.State == "initial" || strings.HasPrefix(s.SubState, "needs-review")
+		}
+
+		if err := res.add(u.ID, text.String(), review, decode); err != nil {
+			return err
+		}
+	}
+
+	for _, g := range groups {
+		if err := res.readUnits20(g.Units, g.Groups, decode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add adds a unit's translation to the result.
+func (res *Result) add(unitID, text string, review bool, decode lpax.TextIDDecoder) error {
+	id, err := DecodeUnitID(unitID, decode)
+	if err != nil {
+		return fmt.Errorf("unit %s: %w", unitID, err)
+	}
+
+	if text == "" {
+		res.Untranslated = append(res.Untranslated, unitID)
+		return nil
+	}
+
+	if review {
+		res.NeedsReview = append(res.NeedsReview, unitID)
+	}
+
+	res.Texts[id] = text
+	return nil
+}
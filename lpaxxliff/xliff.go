@@ -0,0 +1,307 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lpaxxliff exchanges lpax TextMaps with translation tools using XLIFF 1.2 and 2.0 documents.
+//
+// Each Single and Plural text is exchanged as a unit whose id is the encoded Single TextID,
+// with PluralSuffix appended for Plural texts.  Plural category variants are not exchanged.
+package lpaxxliff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+// Version is an XLIFF version.
+type Version string
+
+const (
+	// Version12 is XLIFF 1.2.
+	Version12 = Version("1.2")
+
+	// Version20 is XLIFF 2.0.
+	Version20 = Version("2.0")
+
+	// PluralSuffix is appended to the unit id of Plural texts.
+	PluralSuffix = ":plural"
+
+	namespace12  = "urn:oasis:names:tc:xliff:document:1.2"
+	namespace20  = "urn:oasis:names:tc:xliff:document:2.0"
+	namespaceSLR = "urn:oasis:names:tc:xliff:sizerestriction:2.0"
+)
+
+type (
+	// TextIDEncoder encodes a Single TextID as a unit id.
+	// The encoding must be reversible by the TextIDDecoder used to read the translations back.
+	TextIDEncoder func(id lpax.TextID) string
+
+	// Hint holds information for translators about a text.
+	Hint struct {
+		// Note is a note to the translator.
+		Note string
+
+		// MaxLength is the maximum length of the translation in characters, zero if unrestricted.
+		MaxLength int
+	}
+
+	// Document describes the texts written to an XLIFF document.
+	Document struct {
+		// Version is the XLIFF version written, defaulting to Version20.
+		Version Version
+
+		// Original names the file the texts belong to, such as the pack name.
+		Original string
+
+		// SourceLanguage is the language of Source.
+		SourceLanguage lpax.Tag
+
+		// TargetLanguage is the language the texts are to be translated into.
+		TargetLanguage lpax.Tag
+
+		// Source are the texts to translate.
+		Source lpax.TextMap
+
+		// Target are existing translations and may be nil.
+		Target lpax.TextMap
+
+		// Hints are keyed by the Single or Plural TextID they apply to.
+		Hints map[lpax.TextID]Hint
+
+		// Encode encodes Single TextIDs as unit ids, if nil the id's String version is used.
+		Encode TextIDEncoder
+	}
+
+	// unit is a text to write.
+	unit struct {
+		id     string
+		source string
+		target string
+		hint   Hint
+	}
+)
+
+// Write writes the document as XLIFF.  Units are ordered by id.
+func Write(w io.Writer, d *Document) error {
+	units := d.units()
+
+	var doc interface{}
+	switch d.Version {
+	case Version12:
+		doc = newXliff12(d, units)
+	case Version20, "":
+		doc = newXliff20(d, units)
+	default:
+		return fmt.Errorf("unsupported XLIFF version %q", d.Version)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// units returns the units for the Single and Plural source texts.
+func (d *Document) units() []unit {
+	encode := d.Encode
+	if encode == nil {
+		encode = func(id lpax.TextID) string { return id.String() }
+	}
+
+	units := make([]unit, 0, len(d.Source))
+	for k, text := range d.Source {
+		id, ok := UnitID(k, encode)
+		if !ok {
+			continue
+		}
+
+		units = append(units, unit{id: id, source: text, target: d.Target[k], hint: d.Hints[k]})
+	}
+
+	sort.Slice(units, func(i, j int) bool { return units[i].id < units[j].id })
+	return units
+}
+
+// UnitID returns the unit id of a Single or Plural TextID.  False is returned for other ids
+// such as plural category variants.
+func UnitID(id lpax.TextID, encode TextIDEncoder) (string, bool) {
+	single := id.Single()
+	switch {
+	case id == single:
+		return encode(single), true
+	case id == id.Plural():
+		return encode(single) + PluralSuffix, true
+	}
+	return "", false
+}
+
+// DecodeUnitID returns the Single or Plural TextID of a unit id.
+func DecodeUnitID(unitID string, decode lpax.TextIDDecoder) (lpax.TextID, error) {
+	if key := strings.TrimSuffix(unitID, PluralSuffix); key != unitID {
+		id, err := decode(key)
+		if err != nil {
+			return nil, err
+		}
+		return id.Plural(), nil
+	}
+
+	id, err := decode(unitID)
+	if err != nil {
+		return nil, err
+	}
+	return id.Single(), nil
+}
+
+type (
+	xliff12 struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+		Version string   `xml:"version,attr"`
+		File    file12   `xml:"file"`
+	}
+
+	file12 struct {
+		Original       string        `xml:"original,attr"`
+		SourceLanguage string        `xml:"source-language,attr"`
+		TargetLanguage string        `xml:"target-language,attr,omitempty"`
+		Datatype       string        `xml:"datatype,attr"`
+		Units          []transUnit12 `xml:"body>trans-unit"`
+	}
+
+	transUnit12 struct {
+		ID       string    `xml:"id,attr"`
+		MaxWidth int       `xml:"maxwidth,attr,omitempty"`
+		SizeUnit string    `xml:"size-unit,attr,omitempty"`
+		Source   string    `xml:"source"`
+		Target   *target12 `xml:"target,omitempty"`
+		Note     string    `xml:"note,omitempty"`
+	}
+
+	target12 struct {
+		State string `xml:"state,attr,omitempty"`
+		Text  string `xml:",chardata"`
+	}
+
+	xliff20 struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+		SLR     string   `xml:"xmlns:slr,attr,omitempty"`
+		Version string   `xml:"version,attr"`
+		SrcLang string   `xml:"srcLang,attr"`
+		TrgLang string   `xml:"trgLang,attr,omitempty"`
+		File    file20   `xml:"file"`
+	}
+
+	file20 struct {
+		ID       string    `xml:"id,attr"`
+		Original string    `xml:"original,attr,omitempty"`
+		Profiles *profiles `xml:"slr:profiles,omitempty"`
+		Units    []unit20  `xml:"unit"`
+	}
+
+	profiles struct {
+		GeneralProfile string `xml:"generalProfile,attr"`
+	}
+
+	unit20 struct {
+		ID              string    `xml:"id,attr"`
+		SizeRestriction int       `xml:"slr:sizeRestriction,attr,omitempty"`
+		Notes           []string  `xml:"notes>note,omitempty"`
+		Segment         segment20 `xml:"segment"`
+	}
+
+	segment20 struct {
+		State  string `xml:"state,attr,omitempty"`
+		Source string `xml:"source"`
+		Target string `xml:"target,omitempty"`
+	}
+)
+
+func newXliff12(d *Document, units []unit) *xliff12 {
+	doc := &xliff12{
+		Version: string(Version12),
+		File: file12{
+			Original:       d.Original,
+			SourceLanguage: d.SourceLanguage.String(),
+			Datatype:       "plaintext",
+		},
+	}
+
+	if d.TargetLanguage != language.Und {
+		doc.File.TargetLanguage = d.TargetLanguage.String()
+	}
+
+	for _, u := range units {
+		tu := transUnit12{ID: u.id, Source: u.source, Note: u.hint.Note}
+
+		if u.hint.MaxLength > 0 {
+			tu.MaxWidth, tu.SizeUnit = u.hint.MaxLength, "char"
+		}
+
+		if u.target != "" {
+			tu.Target = &target12{State: "translated", Text: u.target}
+		}
+
+		doc.File.Units = append(doc.File.Units, tu)
+	}
+
+	return doc
+}
+
+func newXliff20(d *Document, units []unit) *xliff20 {
+	doc := &xliff20{
+		Version: string(Version20),
+		SrcLang: d.SourceLanguage.String(),
+		File:    file20{ID: "f1", Original: d.Original},
+	}
+
+	if d.TargetLanguage != language.Und {
+		doc.TrgLang = d.TargetLanguage.String()
+	}
+
+	for _, u := range units {
+		tu := unit20{ID: u.id, Segment: segment20{State: "initial", Source: u.source}}
+
+		if u.hint.Note != "" {
+			tu.Notes = []string{u.hint.Note}
+		}
+
+		if u.hint.MaxLength > 0 {
+			tu.SizeRestriction = u.hint.MaxLength
+			doc.SLR = namespaceSLR
+			doc.File.Profiles = &profiles{GeneralProfile: "xliff:codepoints"}
+		}
+
+		if u.target != "" {
+			tu.Segment.State, tu.Segment.Target = "translated", u.target
+		}
+
+		doc.File.Units = append(doc.File.Units, tu)
+	}
+
+	return doc
+}
@@ -0,0 +1,225 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpaxxliff
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nehemming/lpax"
+	"golang.org/x/text/language"
+)
+
+type testTextID int
+
+func (id testTextID) Single() lpax.TextID {
+	return testTextID(lpax.IntTypeSingle(int(id)))
+}
+
+func (id testTextID) Plural() lpax.TextID {
+	return testTextID(lpax.IntTypePlural(int(id)))
+}
+
+func (id testTextID) String() string {
+	return strconv.Itoa(int(id))
+}
+
+func decodeTestTextID(key string) (lpax.TextID, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, errors.New("bad key")
+	}
+	return testTextID(id), nil
+}
+
+var english = lpax.TextMap{
+	testTextID(1):  "Hello World",
+	testTextID(2):  "%d file",
+	testTextID(-2): "%d files",
+	lpax.ByCategory(testTextID(2), lpax.PluralMany): "many files",
+}
+
+var french = lpax.TextMap{
+	testTextID(1):  "Bonjour le monde",
+	testTextID(-2): "%d fichiers",
+}
+
+func newDocument(version Version) *Document {
+	return &Document{
+		Version:        version,
+		Original:       "example",
+		SourceLanguage: language.English,
+		TargetLanguage: language.French,
+		Source:         english,
+		Target:         french,
+		Hints: map[lpax.TextID]Hint{
+			testTextID(1): {Note: "Greeting", MaxLength: 20},
+		},
+	}
+}
+
+func TestWrite12(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, newDocument(Version12)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	for _, want := range []string{
+		`<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">`,
+		`<file original="example" source-language="en" target-language="fr" datatype="plaintext">`,
+		`<trans-unit id="1" maxwidth="20" size-unit="char">`,
+		`<target state="translated">Bonjour le monde</target>`,
+		`<note>Greeting</note>`,
+		`<trans-unit id="2:plural">`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Error("missing", want, s)
+		}
+	}
+
+	if strings.Count(s, "<trans-unit") != 3 {
+		t.Error("units", s)
+	}
+}
+
+func TestWrite20(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, newDocument(Version20)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	for _, want := range []string{
+		`xmlns:slr="urn:oasis:names:tc:xliff:sizerestriction:2.0"`,
+		`srcLang="en" trgLang="fr"`,
+		`<slr:profiles generalProfile="xliff:codepoints"></slr:profiles>`,
+		`<unit id="1" slr:sizeRestriction="20">`,
+		`<note>Greeting</note>`,
+		`<segment state="initial">`,
+		`<target>%d fichiers</target>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Error("missing", want, s)
+		}
+	}
+}
+
+func TestWriteUnsupportedVersion(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, &Document{Version: "3.0"}); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, version := range []Version{Version12, Version20} {
+		var buf bytes.Buffer
+		if err := Write(&buf, newDocument(version)); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Read(&buf, language.French, decodeTestTextID)
+		if err != nil {
+			t.Fatal(version, err)
+		}
+
+		if res.Version != version || len(res.Texts) != 2 ||
+			res.Texts[testTextID(1)] != "Bonjour le monde" || res.Texts[testTextID(-2)] != "%d fichiers" {
+			t.Error(version, res)
+		}
+
+		if len(res.Untranslated) != 1 || res.Untranslated[0] != "2" || len(res.NeedsReview) != 0 {
+			t.Error(version, "status", res.Untranslated, res.NeedsReview)
+		}
+	}
+}
+
+func TestReadNeedsReview12(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+ <file original="x" source-language="en" target-language="fr" datatype="plaintext">
+  <body>
+   <trans-unit id="1"><source>Hello</source><target state="needs-review-translation">Salut</target></trans-unit>
+   <group><trans-unit id="2"><source>file</source><target>fichier</target></trans-unit></group>
+   <trans-unit id="3"><source>x</source></trans-unit>
+  </body>
+ </file>
+</xliff>`
+
+	res, err := Read(strings.NewReader(doc), language.French, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Texts[testTextID(1)] != "Salut" || res.Texts[testTextID(2)] != "fichier" {
+		t.Error("texts", res.Texts)
+	}
+
+	if len(res.NeedsReview) != 1 || res.NeedsReview[0] != "1" || len(res.Untranslated) != 1 || res.Untranslated[0] != "3" {
+		t.Error("status", res.NeedsReview, res.Untranslated)
+	}
+}
+
+func TestReadNeedsReview20(t *testing.T) {
+	doc := `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="fr">
+ <file id="f1">
+  <unit id="1"><segment state="initial"><source>Hello</source><target>Salut</target></segment></unit>
+  <group id="g"><unit id="2"><segment state="reviewed" subState="needs-review:x"><source>file</source><target>fichier</target></segment></unit></group>
+  <unit id="3"><segment state="final"><source>a </source><target>un </target></segment><segment><source>b</source><target>b</target></segment></unit>
+ </file>
+</xliff>`
+
+	res, err := Read(strings.NewReader(doc), language.French, decodeTestTextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Texts[testTextID(3)] != "un b" || len(res.Texts) != 3 {
+		t.Error("texts", res.Texts)
+	}
+
+	if len(res.NeedsReview) != 2 || len(res.Untranslated) != 0 {
+		t.Error("status", res.NeedsReview, res.Untranslated)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, newDocument(Version20)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Read(bytes.NewReader(buf.Bytes()), language.German, decodeTestTextID); err == nil {
+		t.Error("language mismatch")
+	}
+
+	badDecode := func(string) (lpax.TextID, error) { return nil, errors.New("bad") }
+	if _, err := Read(bytes.NewReader(buf.Bytes()), language.French, badDecode); err == nil {
+		t.Error("decode error")
+	}
+
+	if _, err := Read(strings.NewReader(`<xliff xmlns="urn:other"/>`), language.French, decodeTestTextID); err == nil {
+		t.Error("namespace")
+	}
+
+	if _, err := Read(strings.NewReader(`<xliff`), language.French, decodeTestTextID); err == nil {
+		t.Error("syntax")
+	}
+}
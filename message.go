@@ -0,0 +1,583 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+type (
+	// Message is a parsed ICU MessageFormat pattern.
+	// Messages support named and positional arguments {name} and {0}, the language specific typed arguments
+	// {n, number}, {n, number, integer} and {n, number, percent},
+	// and the complex plural, selectordinal and select arguments including nested arguments,
+	// explicit =N selectors, offset: and the # replacement within plural messages.
+	// {d, date} and {d, time} arguments are not localized, a time.Time is printed using the ISO 8601
+	// layouts 2006-01-02 and 15:04:05 whatever the language and style.
+	Message struct {
+		pattern string
+		nodes   []messageNode
+	}
+
+	// MessageArgs is a set of named arguments passed to Format.
+	MessageArgs map[string]interface{}
+
+	// messageNode is a component of a parsed message.
+	messageNode interface {
+		render(b *strings.Builder, r *messageRenderer)
+	}
+
+	// textNode is literal text.
+	textNode string
+
+	// poundNode is the # number replacement within a plural message.
+	poundNode struct{}
+
+	// argNode is a simple argument optionally with a type and style.
+	argNode struct {
+		name      string
+		argType   string
+		argStyle  string
+		isNumeric bool
+	}
+
+	// pluralNode is a plural or selectordinal argument.
+	pluralNode struct {
+		name     string
+		rules    *plural.Rules
+		offset   float64
+		explicit map[float64][]messageNode
+		forms    map[PluralCategory][]messageNode
+	}
+
+	// selectNode is a select argument.
+	selectNode struct {
+		name  string
+		cases map[string][]messageNode
+	}
+
+	// messageParser parses a pattern into message nodes.
+	messageParser struct {
+		pattern string
+		pos     int
+	}
+
+	// messageRenderer holds the arguments and language used to render a message.
+	messageRenderer struct {
+		langTag Tag
+		printer *message.Printer
+		args    []interface{}
+		numbers []interface{}
+	}
+)
+
+// maxCachedMessages limits the number of parsed messages cached, the cache is cleared when full
+// so patterns no longer used, such as text replaced by a Watcher reload, are released.
+const maxCachedMessages = 1024
+
+var (
+	// messageCache caches parsed messages by pattern.
+	messageCache   = make(map[string]*Message)
+	messageCacheMu sync.RWMutex
+)
+
+// ParseMessage parses an ICU MessageFormat pattern.
+func ParseMessage(pattern string) (*Message, error) {
+	p := &messageParser{pattern: pattern}
+
+	nodes, err := p.parseMessage(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{pattern: pattern, nodes: nodes}, nil
+}
+
+// cachedMessage returns the parsed message for a pattern, parsing it on first use.
+func cachedMessage(pattern string) (*Message, error) {
+	messageCacheMu.RLock()
+	m, ok := messageCache[pattern]
+	messageCacheMu.RUnlock()
+
+	if ok {
+		return m, nil
+	}
+
+	m, err := ParseMessage(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	messageCacheMu.Lock()
+	defer messageCacheMu.Unlock()
+
+	if len(messageCache) >= maxCachedMessages {
+		messageCache = make(map[string]*Message)
+	}
+	messageCache[pattern] = m
+
+	return m, nil
+}
+
+// String returns the pattern the message was parsed from.
+func (m *Message) String() string {
+	return m.pattern
+}
+
+// Format renders the message in the language langTag.
+// Arguments are positional, {0} being the first arg, and named, taken from any MessageArgs passed in args.
+// Missing arguments are rendered as {name}.
+func (m *Message) Format(langTag Tag, args ...interface{}) string {
+	r := &messageRenderer{
+		langTag: langTag,
+		printer: message.NewPrinter(langTag),
+		args:    args,
+	}
+
+	var b strings.Builder
+	renderNodes(&b, r, m.nodes)
+	return b.String()
+}
+
+func renderNodes(b *strings.Builder, r *messageRenderer, nodes []messageNode) {
+	for _, n := range nodes {
+		n.render(b, r)
+	}
+}
+
+func (p *messageParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("message pattern %q offset %d: %s", p.pattern, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *messageParser) atEnd() bool {
+	return p.pos >= len(p.pattern)
+}
+
+func (p *messageParser) peek() byte {
+	return p.pattern[p.pos]
+}
+
+func (p *messageParser) skipSpace() {
+	for !p.atEnd() && unicode.IsSpace(rune(p.peek())) {
+		p.pos++
+	}
+}
+
+func (p *messageParser) expect(c byte) error {
+	p.skipSpace()
+	if p.atEnd() || p.peek() != c {
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+// token reads a name, type or selector terminated by space or pattern syntax.
+func (p *messageParser) token() string {
+	p.skipSpace()
+	start := p.pos
+	for !p.atEnd() {
+		c := p.peek()
+		if unicode.IsSpace(rune(c)) || strings.IndexByte("{},", c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.pattern[start:p.pos]
+}
+
+// parseMessage parses message text up to the end of the pattern or, if nested, the closing brace.
+func (p *messageParser) parseMessage(inPlural, nested bool) ([]messageNode, error) {
+	nodes := make([]messageNode, 0)
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for !p.atEnd() {
+		c := p.peek()
+		switch {
+		case c == '}':
+			if !nested {
+				return nil, p.errorf("unmatched '}'")
+			}
+			flush()
+			return nodes, nil
+		case c == '{':
+			flush()
+			node, err := p.parseArgument()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		case c == '#' && inPlural:
+			flush()
+			nodes = append(nodes, poundNode{})
+			p.pos++
+		case c == '\'':
+			p.parseApostrophe(&text, inPlural)
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	if nested {
+		return nil, p.errorf("unterminated message")
+	}
+
+	flush()
+	return nodes, nil
+}
+
+// parseApostrophe handles the ICU quoting rules, a doubled apostrophe is a literal apostrophe and
+// an apostrophe before a syntax character starts quoted literal text.
+func (p *messageParser) parseApostrophe(text *strings.Builder, inPlural bool) {
+	p.pos++
+	if p.atEnd() {
+		text.WriteByte('\'')
+		return
+	}
+
+	c := p.peek()
+	if c == '\'' {
+		text.WriteByte('\'')
+		p.pos++
+		return
+	}
+
+	if c != '{' && c != '}' && c != '|' && !(c == '#' && inPlural) {
+		text.WriteByte('\'')
+		return
+	}
+
+	for !p.atEnd() {
+		c = p.peek()
+		p.pos++
+		if c != '\'' {
+			text.WriteByte(c)
+			continue
+		}
+		if p.atEnd() || p.peek() != '\'' {
+			return
+		}
+		text.WriteByte('\'')
+		p.pos++
+	}
+}
+
+// parseArgument parses an argument starting at its opening brace.
+func (p *messageParser) parseArgument() (messageNode, error) {
+	p.pos++ // skip {
+
+	name := p.token()
+	if name == "" {
+		return nil, p.errorf("missing argument name")
+	}
+
+	p.skipSpace()
+	if !p.atEnd() && p.peek() == '}' {
+		p.pos++
+		return &argNode{name: name}, nil
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	argType := p.token()
+	switch argType {
+	case "plural":
+		return p.parsePlural(name, plural.Cardinal)
+	case "selectordinal":
+		return p.parsePlural(name, plural.Ordinal)
+	case "select":
+		return p.parseSelect(name)
+	case "":
+		return nil, p.errorf("missing argument type")
+	}
+
+	return p.parseSimple(name, argType)
+}
+
+// parseSimple parses the optional style of a simple typed argument.
+func (p *messageParser) parseSimple(name, argType string) (messageNode, error) {
+	node := &argNode{name: name, argType: argType, isNumeric: argType == "number"}
+
+	p.skipSpace()
+	if !p.atEnd() && p.peek() == ',' {
+		p.pos++
+		start := p.pos
+		for !p.atEnd() && p.peek() != '}' {
+			p.pos++
+		}
+		node.argStyle = strings.TrimSpace(p.pattern[start:p.pos])
+	}
+
+	if err := p.expect('}'); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// parsePlural parses the selectors and messages of a plural or selectordinal argument.
+func (p *messageParser) parsePlural(name string, rules *plural.Rules) (messageNode, error) {
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	node := &pluralNode{
+		name:     name,
+		rules:    rules,
+		explicit: make(map[float64][]messageNode),
+		forms:    make(map[PluralCategory][]messageNode),
+	}
+
+	p.skipSpace()
+	if strings.HasPrefix(p.pattern[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		if _, err := fmt.Sscan(p.token(), &node.offset); err != nil {
+			return nil, p.errorf("invalid offset")
+		}
+	}
+
+	hasOther := false
+	err := p.parseCases(func(selector string, nodes []messageNode) error {
+		if strings.HasPrefix(selector, "=") {
+			var v float64
+			if _, err := fmt.Sscan(selector[1:], &v); err != nil {
+				return p.errorf("invalid explicit selector %q", selector)
+			}
+			node.explicit[v] = nodes
+			return nil
+		}
+
+		category, ok := pluralCategoryByName(selector)
+		if !ok {
+			return p.errorf("invalid plural selector %q", selector)
+		}
+		hasOther = hasOther || category == PluralOther
+		node.forms[category] = nodes
+		return nil
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasOther {
+		return nil, p.errorf("plural argument %q has no other selector", name)
+	}
+
+	return node, nil
+}
+
+// parseSelect parses the cases of a select argument.
+func (p *messageParser) parseSelect(name string) (messageNode, error) {
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	node := &selectNode{name: name, cases: make(map[string][]messageNode)}
+
+	err := p.parseCases(func(selector string, nodes []messageNode) error {
+		node.cases[selector] = nodes
+		return nil
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := node.cases["other"]; !ok {
+		return nil, p.errorf("select argument %q has no other selector", name)
+	}
+
+	return node, nil
+}
+
+// parseCases parses selector {message} pairs up to and including the argument's closing brace.
+func (p *messageParser) parseCases(add func(selector string, nodes []messageNode) error, inPlural bool) error {
+	for {
+		p.skipSpace()
+		if p.atEnd() {
+			return p.errorf("unterminated argument")
+		}
+
+		if p.peek() == '}' {
+			p.pos++
+			return nil
+		}
+
+		selector := p.token()
+		if selector == "" {
+			return p.errorf("missing selector")
+		}
+
+		if err := p.expect('{'); err != nil {
+			return err
+		}
+
+		nodes, err := p.parseMessage(inPlural, true)
+		if err != nil {
+			return err
+		}
+		p.pos++ // skip }
+
+		if err := add(selector, nodes); err != nil {
+			return err
+		}
+	}
+}
+
+// pluralCategoryByName returns the category for a CLDR category name.
+func pluralCategoryByName(name string) (PluralCategory, bool) {
+	for c, n := range pluralCategoryNames {
+		if n == name {
+			return PluralCategory(c), true
+		}
+	}
+	return PluralOther, false
+}
+
+// arg looks up an argument by position or by name in any MessageArgs.
+func (r *messageRenderer) arg(name string) (interface{}, bool) {
+	var index int
+	if _, err := fmt.Sscanf(name, "%d", &index); err == nil && fmt.Sprint(index) == name {
+		if index >= 0 && index < len(r.args) {
+			return r.args[index], true
+		}
+		return nil, false
+	}
+
+	for _, a := range r.args {
+		if named, ok := a.(MessageArgs); ok {
+			if v, found := named[name]; found {
+				return v, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// formatNumber formats a numeric value using the language's number format.
+func (r *messageRenderer) formatNumber(v interface{}, style string) string {
+	switch style {
+	case "integer":
+		if f, ok := toFloat(v); ok {
+			return r.printer.Sprint(number.Decimal(f, number.MaxFractionDigits(0)))
+		}
+	case "percent":
+		return r.printer.Sprint(number.Percent(v))
+	}
+	return r.printer.Sprint(number.Decimal(v))
+}
+
+func (n textNode) render(b *strings.Builder, r *messageRenderer) {
+	b.WriteString(string(n))
+}
+
+func (n poundNode) render(b *strings.Builder, r *messageRenderer) {
+	if len(r.numbers) == 0 {
+		b.WriteByte('#')
+		return
+	}
+	b.WriteString(r.formatNumber(r.numbers[len(r.numbers)-1], ""))
+}
+
+func (n *argNode) render(b *strings.Builder, r *messageRenderer) {
+	v, ok := r.arg(n.name)
+	if !ok {
+		b.WriteString("{" + n.name + "}")
+		return
+	}
+
+	if _, isNumber := toFloat(v); isNumber && (n.isNumeric || n.argType == "") {
+		b.WriteString(r.formatNumber(v, n.argStyle))
+		return
+	}
+
+	if t, isTime := v.(time.Time); isTime {
+		switch n.argType {
+		case "date":
+			b.WriteString(t.Format("2006-01-02"))
+			return
+		case "time":
+			b.WriteString(t.Format("15:04:05"))
+			return
+		}
+	}
+
+	b.WriteString(fmt.Sprint(v))
+}
+
+func (n *pluralNode) render(b *strings.Builder, r *messageRenderer) {
+	v, ok := r.arg(n.name)
+	f, isNumber := toFloat(v)
+	if !ok || !isNumber {
+		b.WriteString("{" + n.name + "}")
+		return
+	}
+
+	nodes, found := n.explicit[f]
+	if !found {
+		category := matchPluralOperands(n.rules, r.langTag, f-n.offset)
+		if nodes, found = n.forms[category]; !found {
+			nodes = n.forms[PluralOther]
+		}
+	}
+
+	var pound interface{} = v
+	if n.offset != 0 {
+		pound = f - n.offset
+	}
+
+	r.numbers = append(r.numbers, pound)
+	renderNodes(b, r, nodes)
+	r.numbers = r.numbers[:len(r.numbers)-1]
+}
+
+func (n *selectNode) render(b *strings.Builder, r *messageRenderer) {
+	v, ok := r.arg(n.name)
+	if !ok {
+		b.WriteString("{" + n.name + "}")
+		return
+	}
+
+	nodes, found := n.cases[fmt.Sprint(v)]
+	if !found {
+		nodes = n.cases["other"]
+	}
+
+	// # is only replaced directly within plural messages
+	numbers := r.numbers
+	r.numbers = nil
+	renderNodes(b, r, nodes)
+	r.numbers = numbers
+}
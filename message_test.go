@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+func TestMessageFormat(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		lang     string
+		args     []interface{}
+		expected string
+	}{
+		{"Hello {0}", "en", []interface{}{"World"}, "Hello World"},
+		{"{1} then {0}", "en", []interface{}{"a", "b"}, "b then a"},
+		{"Hello {user}", "en", []interface{}{MessageArgs{"user": "Bob"}}, "Hello Bob"},
+		{"Hello {user}", "en", nil, "Hello {user}"},
+		{"{0} items", "en", []interface{}{1234}, "1,234 items"},
+		{"{n, number} items", "de", []interface{}{MessageArgs{"n": 1234}}, "1.234 items"},
+		{"{n, number, integer}", "en", []interface{}{MessageArgs{"n": 2.6}}, "3"},
+		{"{n, number, percent}", "en", []interface{}{MessageArgs{"n": 0.25}}, "25%"},
+		{"it''s '{literal}' {0}", "en", []interface{}{1}, "it's {literal} 1"},
+		{"it's", "en", nil, "it's"},
+		{"{0, plural, one {# file} other {# files}}", "en", []interface{}{1}, "1 file"},
+		{"{0, plural, one {# file} other {# files}}", "en", []interface{}{0}, "0 files"},
+		{"{0, plural, one {# fichier} other {# fichiers}}", "fr", []interface{}{0}, "0 fichier"},
+		{"{0, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}", "pl", []interface{}{22}, "22 pliki"},
+		{"{0, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}", "pl", []interface{}{1.5}, "1,5 pliku"},
+		{"{0, plural, one {# file} other {# files}}", "en", []interface{}{10000001}, "10,000,001 files"},
+		{"{0, plural, =0 {no files} one {# file} other {# files}}", "en", []interface{}{0}, "no files"},
+		{
+			"{0, plural, offset:1 =0 {nobody} =1 {{host}} one {{host} and # other} other {{host} and # others}}",
+			"en", []interface{}{3, MessageArgs{"host": "Ann"}}, "Ann and 2 others",
+		},
+		{"{0, plural, other {'#' #}}", "en", []interface{}{2}, "# 2"},
+		{"{0, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}", "en", []interface{}{23}, "23rd"},
+		{"{g, select, female {her} male {his} other {their}} car", "en", []interface{}{MessageArgs{"g": "male"}}, "his car"},
+		{"{g, select, female {her} other {their}} car", "en", []interface{}{MessageArgs{"g": "x"}}, "their car"},
+		{
+			"{g, select, female {{n, plural, one {her file} other {her # files}}} other {{n, plural, one {a file} other {# files}}}}",
+			"en", []interface{}{MessageArgs{"g": "female", "n": 2}}, "her 2 files",
+		},
+		{"{0, plural, other {{1, select, other {#}}}}", "en", []interface{}{2, "a"}, "#"},
+		{"{d, date} {d, time}", "en", []interface{}{MessageArgs{"d": time.Date(2021, 6, 2, 13, 4, 5, 0, time.UTC)}}, "2021-06-02 13:04:05"},
+		{"{d, date, long}", "de", []interface{}{MessageArgs{"d": time.Date(2021, 6, 2, 13, 4, 5, 0, time.UTC)}}, "2021-06-02"},
+	}
+
+	for _, test := range tests {
+		m, err := ParseMessage(test.pattern)
+		if err != nil {
+			t.Error(test.pattern, err)
+			continue
+		}
+
+		s := m.Format(language.MustParse(test.lang), test.args...)
+		if s != test.expected {
+			t.Errorf("%s: got %q expected %q", test.pattern, s, test.expected)
+		}
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"{",
+		"}",
+		"{}",
+		"{0,}",
+		"{0, plural, one {x}}",
+		"{0, plural, lots {x} other {y}}",
+		"{0, plural, =x {x} other {y}}",
+		"{0, plural, offset:x other {y}}",
+		"{0, select, a {x}}",
+		"{0, select, other {x}",
+		"{0, select, other x}",
+		"{0 1}",
+	} {
+		if _, err := ParseMessage(pattern); err == nil {
+			t.Error("no error", pattern)
+		}
+	}
+}
+
+func TestCachedMessage(t *testing.T) {
+	m, err := cachedMessage("cached {0}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := cachedMessage("cached {0}")
+	if m != c {
+		t.Error("not cached")
+	}
+
+	if m.String() != "cached {0}" {
+		t.Error("String", m)
+	}
+}
+
+func TestCachedMessageBounded(t *testing.T) {
+	for i := 0; i <= maxCachedMessages; i++ {
+		if _, err := cachedMessage(fmt.Sprintf("bounded %d {0}", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messageCacheMu.RLock()
+	n := len(messageCache)
+	messageCacheMu.RUnlock()
+
+	if n > maxCachedMessages {
+		t.Error("cache size", n)
+	}
+}
+
+func TestCtxFormat(t *testing.T) {
+	tm := TextMap{
+		Hello: "{count, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}",
+		Args:  "{0",
+	}
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.Polish, tm))
+
+	s := CtxFormat(ctx, Hello, MessageArgs{"count": 5})
+	if s != "5 plików" {
+		t.Error("Mismatch ", s)
+	}
+
+	s = CtxFormat(ctx, Args, 1)
+	if s != "3:1" {
+		t.Error("Invalid pattern ", s)
+	}
+
+	s = CtxFormat(ctx, None, 1, "a")
+	if s != "1:1 a" {
+		t.Error("Missing ", s)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	s := Format(Hello)
+	if s != "Hello World" {
+		t.Error("Mismatch ", s)
+	}
+}
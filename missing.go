@@ -0,0 +1,216 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MissingMode selects how the finders created by a registry handle text missing when it is rendered by
+// Text, Sprintf, Errorf and the other formatting functions.  Find is not affected.
+type MissingMode int
+
+const (
+	// MissingFallback Text returns an empty string and the formatting functions print the string version
+	// of the id followed by a space separated %v version of each arg.
+	MissingFallback = MissingMode(iota)
+
+	// MissingMarker Text returns a visible marker, e.g. [MISSING:pkg-00042], and the formatting functions
+	// print the marker followed by a space separated %v version of each arg.
+	MissingMarker
+
+	// MissingPanic rendering missing text panics with a *MissingTextError.  Errorf, CtxErrorf and NewError
+	// panic when the error is created rather than when it is printed.
+	MissingPanic
+
+	// MissingError missing text is rendered as MissingFallback, however the errors created by Errorf,
+	// CtxErrorf and NewError for text missing when they are created match ErrMissingText using errors.Is
+	// and *MissingTextError using errors.As.
+	MissingError
+)
+
+// String returns the name of the mode.
+func (m MissingMode) String() string {
+	switch m {
+	case MissingFallback:
+		return "fallback"
+	case MissingMarker:
+		return "marker"
+	case MissingPanic:
+		return "panic"
+	case MissingError:
+		return "error"
+	}
+	return fmt.Sprintf("MissingMode(%d)", int(m))
+}
+
+// ErrMissingText is matched by MissingTextErrors using errors.Is.
+var ErrMissingText = errors.New("missing text")
+
+// MissingTextError reports text missing from a finder.
+type MissingTextError struct {
+	// Language is the language of the finder.
+	Language Tag
+
+	// ID is the id of the missing text.
+	ID TextID
+}
+
+// Error describes the missing text.
+func (e *MissingTextError) Error() string {
+	return fmt.Sprintf("missing %s text %s", e.Language, KeyName(e.ID))
+}
+
+// Unwrap returns ErrMissingText.
+func (e *MissingTextError) Unwrap() error {
+	return ErrMissingText
+}
+
+// MissingPolicy configures how missing text is handled.  A registry's policy is set by the SetMissingPolicy
+// method of MissingRegistry and may be replaced for a single finder by passing a MissingPolicy to New.
+type MissingPolicy struct {
+	// Mode selects how missing text is rendered.
+	Mode MissingMode
+
+	// Hook, if not nil, is called each time missing text is rendered, before a MissingPanic panic,
+	// typically to log the miss.  It may be called concurrently.
+	Hook func(*MissingTextError)
+}
+
+// MissingRegistry is implemented by registries whose finders apply a MissingPolicy and count their misses,
+// such as those created by NewRegistry.
+type MissingRegistry interface {
+	// SetMissingPolicy sets how the finders created by the registry handle text missing when rendered.
+	// A finder's policy may be replaced by passing a MissingPolicy to New.
+	SetMissingPolicy(policy MissingPolicy)
+
+	// Misses returns the number of times the text of each TextID has been missing when rendered by the
+	// registry's finders since the registry was created or ResetMisses was called.
+	Misses() map[TextID]int
+
+	// ResetMisses clears the counts returned by Misses.
+	ResetMisses()
+}
+
+// missingMarker returns the marker rendered for missing text by the MissingMarker mode.
+func missingMarker(id TextID) string {
+	return "[MISSING:" + KeyName(id) + "]"
+}
+
+// missingHandler applies a registry's MissingPolicy and counts the misses of its finders.
+type missingHandler struct {
+	policy atomic.Value // MissingPolicy
+	mu     sync.Mutex
+	counts map[TextID]int
+}
+
+func newMissingHandler() *missingHandler {
+	h := &missingHandler{counts: make(map[TextID]int)}
+	h.policy.Store(MissingPolicy{})
+	return h
+}
+
+// current returns the finder's policy if it has one, otherwise the registry's.
+func (h *missingHandler) current(override *MissingPolicy) MissingPolicy {
+	if override != nil {
+		return *override
+	}
+	return h.policy.Load().(MissingPolicy)
+}
+
+// report counts a miss and calls the policy's hook, panicking if the policy is MissingPanic.
+// The mode of the policy is returned.
+func (h *missingHandler) report(override *MissingPolicy, langTag Tag, id TextID) MissingMode {
+	h.mu.Lock()
+	h.counts[id]++
+	h.mu.Unlock()
+
+	policy := h.current(override)
+	if policy.Hook == nil && policy.Mode != MissingPanic {
+		return policy.Mode
+	}
+
+	err := &MissingTextError{Language: langTag, ID: id}
+	if policy.Hook != nil {
+		policy.Hook(err)
+	}
+
+	if policy.Mode == MissingPanic {
+		panic(err)
+	}
+
+	return policy.Mode
+}
+
+// misses returns a copy of the miss counts.
+func (h *missingHandler) misses() map[TextID]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make(map[TextID]int, len(h.counts))
+	for id, n := range h.counts {
+		counts[id] = n
+	}
+	return counts
+}
+
+// reset clears the miss counts.
+func (h *missingHandler) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts = make(map[TextID]int)
+}
+
+// missingReporter is implemented by finders applying a MissingPolicy.
+type missingReporter interface {
+	// reportMissing reports that the text of id was missing when rendered, returning the policy's mode.
+	reportMissing(id TextID) MissingMode
+
+	// missingMode returns the mode of the finder's policy.
+	missingMode() MissingMode
+}
+
+// reportMissing reports missing text to finders applying a MissingPolicy, returning the mode to apply.
+func reportMissing(tf TextFinder, id TextID) MissingMode {
+	if r, ok := tf.(missingReporter); ok {
+		return r.reportMissing(id)
+	}
+	return MissingFallback
+}
+
+// missingFormat reports that the text of id is missing from tf and returns the format and args printed
+// in its place, the string version of the id, or the marker, followed by a space separated %v version of each arg.
+func missingFormat(tf TextFinder, id TextID, args []interface{}) (string, []interface{}) {
+	return formatMissing(reportMissing(tf, id), id, args)
+}
+
+// formatMissing returns the format and args printed in place of the missing text of id by the mode.
+func formatMissing(mode MissingMode, id TextID, args []interface{}) (string, []interface{}) {
+	f := "%s:"
+	args = append([]interface{}{id}, args...)
+
+	if mode == MissingMarker {
+		f, args[0] = "%s ", missingMarker(id)
+	}
+
+	return strings.TrimRight(f+strings.Repeat("%v ", len(args)-1), " "), args
+}
@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// missingTestRegistry is a registry applying a MissingPolicy.
+type missingTestRegistry interface {
+	TextRegistry
+	MissingRegistry
+}
+
+func newMissingRegistry() (missingTestRegistry, context.Context) {
+	r := NewRegistry()
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		return TextMap{Hello: "Hello World"}
+	}, Package, language.English)
+
+	return r.(missingTestRegistry), WithContext(context.Background(), NewFinder(r, language.English))
+}
+
+func TestMissingFallback(t *testing.T) {
+	r, ctx := newMissingRegistry()
+
+	if s := CtxSprintf(ctx, Args, 1, 2); s != "3:1 2" {
+		t.Error("sprintf", s)
+	}
+
+	if s := NewFinder(r).Text(Args); s != "" {
+		t.Error("text", s)
+	}
+
+	// Find is a probe and is not counted
+	if _, ok := r.Find(Args); ok {
+		t.Error("found")
+	}
+
+	if s := CtxSprintf(ctx, Hello); s != "Hello World" {
+		t.Error("hello", s)
+	}
+
+	if misses := r.Misses(); len(misses) != 1 || misses[Args] != 2 {
+		t.Error("misses", misses)
+	}
+
+	r.ResetMisses()
+	if misses := r.Misses(); len(misses) != 0 {
+		t.Error("reset", misses)
+	}
+}
+
+func TestMissingMarker(t *testing.T) {
+	r, ctx := newMissingRegistry()
+	r.SetMissingPolicy(MissingPolicy{Mode: MissingMarker})
+
+	if s := CtxSprintf(ctx, Args, 1, 2); s != "[MISSING:3] 1 2" {
+		t.Error("sprintf", s)
+	}
+
+	if s := CtxSprintfCount(ctx, Args, 2); s != "[MISSING:3]" {
+		t.Error("count", s)
+	}
+
+	if s := NewFinder(r, language.English).Text(Args.Plural()); s != "[MISSING:3:plural]" {
+		t.Error("text", s)
+	}
+
+	if s := CtxErrorf(ctx, Args, "x").Error(); s != "[MISSING:3] x" {
+		t.Error("error", s)
+	}
+
+	if s := CtxSprintNamed(ctx, Args, map[string]interface{}{"n": 1}); s != "[MISSING:3]:n=1" {
+		t.Error("named", s)
+	}
+
+	if s := CtxFormat(ctx, Args); s != "[MISSING:3]" {
+		t.Error("format", s)
+	}
+
+	if misses := r.Misses(); misses[Args] != 5 || misses[Args.Plural()] != 1 {
+		t.Error("misses", misses)
+	}
+}
+
+func TestMissingPanic(t *testing.T) {
+	r, _ := newMissingRegistry()
+	r.SetMissingPolicy(MissingPolicy{Mode: MissingPanic})
+
+	defer func() {
+		err, ok := recover().(*MissingTextError)
+		if !ok || err.ID != Args || err.Language != language.English || !errors.Is(err, ErrMissingText) {
+			t.Error("recovered", err)
+		}
+
+		if s := err.Error(); s != "missing en text 3" {
+			t.Error(s)
+		}
+	}()
+
+	NewFinder(r, language.English).Text(Args)
+	t.Error("no panic")
+}
+
+func TestMissingPanicCreatingError(t *testing.T) {
+	r, ctx := newMissingRegistry()
+	r.SetMissingPolicy(MissingPolicy{Mode: MissingPanic})
+
+	defer func() {
+		if err, ok := recover().(*MissingTextError); !ok || err.ID != Args {
+			t.Error("recovered", err)
+		}
+	}()
+
+	// the panic is raised creating the error, not when fmt prints it
+	_ = CtxErrorf(ctx, Args, 1)
+	t.Error("no panic")
+}
+
+func TestMissingError(t *testing.T) {
+	r, ctx := newMissingRegistry()
+	r.SetMissingPolicy(MissingPolicy{Mode: MissingError})
+
+	err := CtxErrorf(ctx, Args, 1)
+	if !errors.Is(err, ErrMissingText) || !errors.Is(err, NewError(Args)) {
+		t.Error("is", err)
+	}
+
+	var missing *MissingTextError
+	if !errors.As(err, &missing) || missing.ID != Args {
+		t.Error("as", missing)
+	}
+
+	if s := err.Error(); s != "3:1" {
+		t.Error("rendered", s)
+	}
+
+	// the miss is counted once when the error is created, not each time it is rendered
+	_ = err.Error()
+	if misses := r.Misses(); misses[Args] != 1 {
+		t.Error("misses", misses)
+	}
+
+	if err := CtxErrorf(ctx, Hello); errors.Is(err, ErrMissingText) || errors.As(err, &missing) {
+		t.Error("found", err)
+	}
+}
+
+func TestMissingHookAndFinderPolicy(t *testing.T) {
+	r, _ := newMissingRegistry()
+
+	var reported []*MissingTextError
+	r.SetMissingPolicy(MissingPolicy{Hook: func(err *MissingTextError) {
+		reported = append(reported, err)
+	}})
+
+	if s := NewFinder(r, language.English).Text(None); s != "" {
+		t.Error("text", s)
+	}
+
+	// a finder's policy replaces the registry's
+	tf := NewFinder(r, language.English, MissingPolicy{Mode: MissingMarker})
+	if s := tf.Text(None); s != "[MISSING:1]" {
+		t.Error("finder", s)
+	}
+
+	if len(reported) != 1 || reported[0].ID != None {
+		t.Error("hook", reported)
+	}
+
+	if misses := r.Misses(); misses[None] != 2 {
+		t.Error("misses", misses)
+	}
+
+	// live finders apply the policy of the current finder
+	ctx := WithContext(context.Background(), NewLiveFinder(r, language.English, MissingPolicy{Mode: MissingMarker}))
+	if s := CtxSprintf(ctx, None); s != "[MISSING:1]" {
+		t.Error("live", s)
+	}
+}
+
+func TestMissingModeString(t *testing.T) {
+	if MissingPanic.String() != "panic" || MissingMode(9).String() != "MissingMode(9)" {
+		t.Error(MissingPanic, MissingMode(9))
+	}
+}
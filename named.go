@@ -0,0 +1,163 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NamedArgs converts a map with string keys or a struct into MessageArgs.
+// Struct fields are named by their `lpax:"name"` tag or, if not tagged, their field name.
+// Fields tagged `lpax:"-"` and unexported fields are skipped.
+// A nil value or any other type returns empty MessageArgs.
+func NamedArgs(values interface{}) MessageArgs {
+	if ma, ok := values.(MessageArgs); ok {
+		return ma
+	}
+
+	args := make(MessageArgs)
+
+	rv := reflect.ValueOf(values)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return args
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			args[iter.Key().String()] = iter.Value().Interface()
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("lpax"); ok {
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			args[name] = rv.Field(i).Interface()
+		}
+	}
+
+	return args
+}
+
+// SprintNamed renders the text taken from the default text finder replacing {name} placeholders
+// with the named values of a map or struct, see NamedArgs.  The text is a MessageFormat pattern
+// so translators may reorder the placeholders freely.
+// If the string is not found the string version of the id is printed along with a
+// space separated name=value version of each value, ordered by name.
+func SprintNamed(id TextID, values interface{}) string {
+	return sprintNamed(Default(), id, values)
+}
+
+// CtxSprintNamed is identical to SprintNamed except the text is taken from the text finder
+// linked to the passed context. If the context has no finder the default finder is used.
+func CtxSprintNamed(ctx context.Context, id TextID, values interface{}) string {
+	return sprintNamed(FromContext(ctx), id, values)
+}
+
+// ErrorNamed is identical to SprintNamed except an error is returned.
+// The returned error is an *Error which may be rendered again in other languages.
+func ErrorNamed(id TextID, values interface{}) error {
+	return newNamedError(nil, id, values)
+}
+
+// CtxErrorNamed is identical to CtxSprintNamed except an error is returned.
+// The returned error is an *Error which may be rendered again in other languages.
+func CtxErrorNamed(ctx context.Context, id TextID, values interface{}) error {
+	return newNamedError(FromContext(ctx), id, values)
+}
+
+// newNamedError creates an Error rendering the named values, see newError.
+func newNamedError(tf TextFinder, id TextID, values interface{}) *Error {
+	e := newError(tf, id, nil)
+
+	e.Named = NamedArgs(values)
+	if e.Named == nil {
+		e.Named = make(MessageArgs)
+	}
+
+	return e
+}
+
+func sprintNamed(tf TextFinder, id TextID, values interface{}) string {
+	args := NamedArgs(values)
+
+	f, ok := tf.Find(id)
+	if !ok {
+		return formatMissingNamed(reportMissing(tf, id), id, args)
+	}
+
+	return formatNamed(tf, id, f, args)
+}
+
+// formatNamed renders the MessageFormat pattern f of id found by tf, an invalid pattern is printed as
+// if the text was missing.
+func formatNamed(tf TextFinder, id TextID, f string, args MessageArgs) string {
+	if m, err := cachedMessage(f); err == nil {
+		return m.Format(finderLanguage(tf), args)
+	}
+
+	return formatMissingNamed(MissingFallback, id, args)
+}
+
+// formatMissingNamed returns the text printed by the mode in place of the missing text of id, the string
+// version of the id, or the marker, followed by a space separated name=value version of each value.
+func formatMissingNamed(mode MissingMode, id TextID, args MessageArgs) string {
+	var name interface{} = id
+	if mode == MissingMarker {
+		name = missingMarker(id)
+	}
+
+	names := args.names()
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, args[name]))
+	}
+
+	return fmt.Sprintf("%s:%s", name, strings.Join(pairs, " "))
+}
+
+// names returns the names of the args in order.
+func (args MessageArgs) names() []string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
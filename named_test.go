@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+type namedValues struct {
+	User    string `lpax:"user"`
+	Count   int    `lpax:"count"`
+	Skipped string `lpax:"-"`
+	Plain   string
+	hidden  string
+}
+
+func TestNamedArgsStruct(t *testing.T) {
+	args := NamedArgs(&namedValues{User: "Bob", Count: 3, Skipped: "x", Plain: "p", hidden: "h"})
+
+	if len(args) != 3 || args["user"] != "Bob" || args["count"] != 3 || args["Plain"] != "p" {
+		t.Error("args", args)
+	}
+}
+
+func TestNamedArgsMap(t *testing.T) {
+	args := NamedArgs(map[string]int{"count": 3})
+	if len(args) != 1 || args["count"] != 3 {
+		t.Error("args", args)
+	}
+
+	if args = NamedArgs(map[int]int{1: 3}); len(args) != 0 {
+		t.Error("int keys", args)
+	}
+
+	if args = NamedArgs(nil); len(args) != 0 {
+		t.Error("nil", args)
+	}
+}
+
+var germanNamed = TextMap{
+	Hello: "{count, plural, one {# Datei} other {# Dateien}} von {user} gelöscht",
+}
+
+func TestCtxSprintNamed(t *testing.T) {
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.German, germanNamed))
+
+	s := CtxSprintNamed(ctx, Hello, namedValues{User: "Bob", Count: 2})
+	if s != "2 Dateien von Bob gelöscht" {
+		t.Error("Mismatch ", s)
+	}
+
+	s = CtxSprintNamed(ctx, Args, map[string]interface{}{"user": "Bob", "count": 2})
+	if s != "3:count=2 user=Bob" {
+		t.Error("Missing ", s)
+	}
+
+	err := CtxErrorNamed(ctx, Hello, map[string]interface{}{"user": "Ann", "count": 1})
+	if err.Error() != "1 Datei von Ann gelöscht" {
+		t.Error("Error ", err)
+	}
+}
+
+func TestErrorNamedIsError(t *testing.T) {
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.German, germanNamed))
+	err := CtxErrorNamed(ctx, Hello, namedValues{User: "Ann", Count: 2, Plain: io.EOF.Error()})
+
+	var e *Error
+	if !errors.As(err, &e) || e.Code() != "2" || !errors.Is(err, NewError(Hello)) {
+		t.Fatal("not an Error", err)
+	}
+
+	english := TextMap{Hello: "{count, plural, one {# file} other {# files}} deleted by {user}"}
+	if s := e.Render(NewLanguageFinder(language.English, english)); s != "2 files deleted by Ann" {
+		t.Error("Render", s)
+	}
+
+	if s := e.Render(TextMap{}); s != "2:Plain=EOF count=2 user=Ann" {
+		t.Error("Render missing", s)
+	}
+
+	if errors.Is(ErrorNamed(Args, MessageArgs{"cause": io.EOF}), io.EOF) {
+		t.Error("Unwrap named")
+	}
+}
+
+func TestSprintNamed(t *testing.T) {
+	s := SprintNamed(None, MessageArgs{"user": "Bob"})
+	if s != "1:user=Bob" {
+		t.Error("Mismatch ", s)
+	}
+
+	err := ErrorNamed(None, nil)
+	if err.Error() != "1:" {
+		t.Error("Error ", err)
+	}
+}
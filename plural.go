@@ -0,0 +1,258 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// PluralCategory is a CLDR plural category used to select the variant of a message
+// matching a count in a given language.
+type PluralCategory int
+
+const (
+	// PluralOther is the general plural category, all languages support it.
+	PluralOther = PluralCategory(iota)
+
+	// PluralZero is used by languages with a distinct form for zero, e.g. Arabic and Welsh.
+	PluralZero
+
+	// PluralOne is the singular category, in French it also covers zero.
+	PluralOne
+
+	// PluralTwo is the dual category, e.g. Arabic, Welsh and Slovenian.
+	PluralTwo
+
+	// PluralFew is used by languages such as Polish, Russian and Arabic for small quantities.
+	PluralFew
+
+	// PluralMany is used by languages such as Polish, Russian and Arabic for large quantities.
+	PluralMany
+)
+
+const (
+	// pluralModulo is the modulus the plural rules accept in place of operands too large to fit an int.
+	// It is a multiple of every modulus used by the rules so reducing by it leaves the category unchanged.
+	pluralModulo = 10000000
+
+	// pluralDigits is the number of digits in pluralModulo.
+	pluralDigits = 7
+)
+
+// pluralCategoryNames maps the categories to their CLDR names.
+var pluralCategoryNames = [...]string{
+	PluralOther: "other",
+	PluralZero:  "zero",
+	PluralOne:   "one",
+	PluralTwo:   "two",
+	PluralFew:   "few",
+	PluralMany:  "many",
+}
+
+// String returns the CLDR name of the category.
+func (c PluralCategory) String() string {
+	if c < 0 || int(c) >= len(pluralCategoryNames) {
+		return fmt.Sprintf("PluralCategory(%d)", int(c))
+	}
+	return pluralCategoryNames[c]
+}
+
+// pluralTextID is the TextID key of a cardinal or ordinal plural category variant of a message.
+type pluralTextID struct {
+	id       TextID
+	category PluralCategory
+	ordinal  bool
+}
+
+// Single returns the single version of the underlying message id.
+func (id pluralTextID) Single() TextID {
+	return id.id.Single()
+}
+
+// Plural returns the plural version of the underlying message id.
+func (id pluralTextID) Plural() TextID {
+	return id.id.Plural()
+}
+
+// String returns the underlying message id followed by the category, e.g. pkg-00042[few]
+// or pkg-00042[ordinal-few] for ordinal variants.
+func (id pluralTextID) String() string {
+	if id.ordinal {
+		return fmt.Sprintf("%s[ordinal-%s]", id.id, id.category)
+	}
+	return fmt.Sprintf("%s[%s]", id.id, id.category)
+}
+
+// ByCategory returns the TextID key used to store the plural category variant of a message in a TextMap.
+// The key is the same for the single and plural versions of id.
+func ByCategory(id TextID, category PluralCategory) TextID {
+	return pluralTextID{id: id.Single(), category: category}
+}
+
+// ByOrdinalCategory returns the TextID key used to store the ordinal category variant of a message in a TextMap,
+// e.g. the PluralTwo variant "%dnd place" in english.
+// The key is the same for the single and plural versions of id.
+func ByOrdinalCategory(id TextID, category PluralCategory) TextID {
+	return pluralTextID{id: id.Single(), category: category, ordinal: true}
+}
+
+// ByOrdinal returns the ordinal variant key of a message for position n in the language langTag.
+func ByOrdinal(id TextID, langTag Tag, n int) TextID {
+	return ByOrdinalCategory(id, OrdinalCategoryOf(langTag, n))
+}
+
+// PluralCategoryOf returns the CLDR cardinal plural category of count in the language langTag.
+func PluralCategoryOf(langTag Tag, count int) PluralCategory {
+	return matchPlural(plural.Cardinal, langTag, count)
+}
+
+// OrdinalCategoryOf returns the CLDR ordinal plural category of position n in the language langTag.
+// In english 1, 21 are PluralOne, 2, 22 PluralTwo, 3, 23 PluralFew and all other positions PluralOther.
+func OrdinalCategoryOf(langTag Tag, n int) PluralCategory {
+	return matchPlural(plural.Ordinal, langTag, n)
+}
+
+func matchPlural(rules *plural.Rules, langTag Tag, count int) PluralCategory {
+	if count < 0 {
+		if -count < 0 {
+			// the most negative int has no positive counterpart, shifting it by the modulus keeps its category
+			count += pluralModulo
+		}
+		count = -count
+	}
+
+	return pluralCategoryOfForm(rules.MatchPlural(langTag, count, 0, 0, 0, 0))
+}
+
+// matchPluralOperands returns the category of a possibly fractional number, e.g. 1.5.
+func matchPluralOperands(rules *plural.Rules, langTag Tag, v float64) PluralCategory {
+	digits := strconv.FormatFloat(math.Abs(v), 'f', -1, 64)
+
+	intPart, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fraction = digits[:i], digits[i+1:]
+	}
+
+	return pluralCategoryOfForm(rules.MatchPlural(langTag, pluralOperand(intPart), len(fraction), len(fraction),
+		pluralOperand("0"+fraction), pluralOperand("0"+fraction)))
+}
+
+// pluralOperand converts the digits of an operand to an int.  Operands too large to fit an int keep their
+// low order digits, offset by the modulus so they still compare as large numbers.
+func pluralOperand(digits string) int {
+	if n, err := strconv.Atoi(digits); err == nil {
+		return n
+	}
+
+	n, _ := strconv.Atoi(digits[len(digits)-pluralDigits:])
+	return n + pluralModulo
+}
+
+func pluralCategoryOfForm(form plural.Form) PluralCategory {
+	switch form {
+	case plural.Zero:
+		return PluralZero
+	case plural.One:
+		return PluralOne
+	case plural.Two:
+		return PluralTwo
+	case plural.Few:
+		return PluralFew
+	case plural.Many:
+		return PluralMany
+	default:
+		return PluralOther
+	}
+}
+
+// toFloat converts any integer or float kind to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
+
+// finderLanguage returns the language of the finder, or the DefaultLanguage
+// if the finder does not implement LanguageFinder.
+func finderLanguage(tf TextFinder) Tag {
+	if lf, ok := tf.(LanguageFinder); ok {
+		return lf.Language()
+	}
+	return language.MustParse(DefaultLanguage)
+}
+
+// FindCount looks up the variant of textID matching count using the CLDR plural rules of the finder's language.
+// If the finder has no variant for the count's category the Single version is used for the One category and the Plural
+// version for all other categories, falling back to the other version if the preferred one is missing.
+func FindCount(tf TextFinder, textID TextID, count int) (t string, found bool) {
+	category := PluralCategoryOf(finderLanguage(tf), count)
+
+	if t, found = tf.Find(ByCategory(textID, category)); found {
+		return t, found
+	}
+
+	return findSingleOrPlural(tf, textID, category == PluralOne)
+}
+
+// findSingleOrPlural finds the single or plural version of the text id, trying the other version if not found.
+func findSingleOrPlural(tf TextFinder, textID TextID, single bool) (string, bool) {
+	preferred, other := textID.Plural(), textID.Single()
+	if single {
+		preferred, other = other, preferred
+	}
+
+	if t, found := tf.Find(preferred); found {
+		return t, found
+	}
+
+	return tf.Find(other)
+}
+
+// FindOrdinal looks up the ordinal variant of textID for position n using the CLDR ordinal rules of the finder's language.
+// If the finder has no variant for the position's category the PluralOther ordinal variant is used, followed
+// by the Single and then the Plural version of textID.
+func FindOrdinal(tf TextFinder, textID TextID, n int) (t string, found bool) {
+	category := OrdinalCategoryOf(finderLanguage(tf), n)
+
+	if t, found = tf.Find(ByOrdinalCategory(textID, category)); found {
+		return t, found
+	}
+
+	if category != PluralOther {
+		if t, found = tf.Find(ByOrdinalCategory(textID, PluralOther)); found {
+			return t, found
+		}
+	}
+
+	return findSingleOrPlural(tf, textID, true)
+}
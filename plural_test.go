@@ -0,0 +1,312 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// minInt is the most negative int, math.MinInt64 on 64 bit platforms.
+const minInt = -int(^uint(0)>>1) - 1
+
+func TestPluralCategoryOf(t *testing.T) {
+	tests := []struct {
+		lang     string
+		count    int
+		category PluralCategory
+	}{
+		{"en", 0, PluralOther},
+		{"en", 1, PluralOne},
+		{"en", 2, PluralOther},
+		{"fr", 0, PluralOne},
+		{"fr", 1, PluralOne},
+		{"fr", 2, PluralOther},
+		{"pl", 1, PluralOne},
+		{"pl", 3, PluralFew},
+		{"pl", 5, PluralMany},
+		{"pl", 12, PluralMany},
+		{"pl", 22, PluralFew},
+		{"ru", 21, PluralOne},
+		{"ru", 11, PluralMany},
+		{"ar", 0, PluralZero},
+		{"ar", 2, PluralTwo},
+		{"ar", 3, PluralFew},
+		{"ar", 11, PluralMany},
+		{"ar", 100, PluralOther},
+		{"cy", 6, PluralMany},
+		{"ja", 1, PluralOther},
+		{"pl", -3, PluralFew},
+		{"en", 10000000, PluralOther},
+		{"en", 10000001, PluralOther},
+		{"en", 20000001, PluralOther},
+		{"en", -10000001, PluralOther},
+		{"fr", 10000000, PluralOther},
+		{"fr", 10000001, PluralOther},
+		{"pl", 10000002, PluralFew},
+		{"pl", 10000012, PluralMany},
+		{"ru", 10000001, PluralOne},
+		{"ru", 10000011, PluralMany},
+		{"en", minInt, PluralOther},
+		{"pl", minInt, PluralMany},
+	}
+
+	for _, test := range tests {
+		c := PluralCategoryOf(language.MustParse(test.lang), test.count)
+		if c != test.category {
+			t.Error(test.lang, test.count, c, "expected", test.category)
+		}
+	}
+}
+
+func TestPluralCategoryString(t *testing.T) {
+	if s := PluralFew.String(); s != "few" {
+		t.Error("few", s)
+	}
+
+	if s := PluralCategory(99).String(); s != "PluralCategory(99)" {
+		t.Error("99", s)
+	}
+}
+
+func TestByCategorySingleAndPluralMatch(t *testing.T) {
+	if ByCategory(Hello, PluralFew) != ByCategory(Hello.Plural(), PluralFew) {
+		t.Error("single and plural keys differ")
+	}
+
+	if ByCategory(Hello, PluralFew) == ByCategory(Hello, PluralMany) {
+		t.Error("categories keys match")
+	}
+
+	if s := ByCategory(Hello, PluralFew).String(); s != "2[few]" {
+		t.Error("String", s)
+	}
+}
+
+var polishFiles = TextMap{
+	Hello:                         "%d plik",
+	-Hello:                        "%d pliki",
+	ByCategory(Hello, PluralMany): "%d plików",
+}
+
+func TestFindCountPolish(t *testing.T) {
+	tf := NewLanguageFinder(language.Polish, polishFiles)
+
+	for count, expected := range map[int]string{
+		1:  "%d plik",
+		2:  "%d pliki",
+		5:  "%d plików",
+		22: "%d pliki",
+	} {
+		f, ok := FindCount(tf, Hello, count)
+		if !ok || f != expected {
+			t.Error(count, f, ok)
+		}
+	}
+}
+
+func TestFindCountFallsBackToOtherVersion(t *testing.T) {
+	tf := NewLanguageFinder(language.English, TextMap{Args: "single"})
+
+	f, ok := FindCount(tf, Args, 3)
+	if !ok || f != "single" {
+		t.Error("plural", f, ok)
+	}
+
+	_, ok = FindCount(tf, Hello, 1)
+	if ok {
+		t.Error("found missing")
+	}
+}
+
+func TestFindCountTextMapUsesDefaultLanguage(t *testing.T) {
+	f, ok := FindCount(polishFiles, Hello, 5)
+	if !ok || f != "%d pliki" {
+		t.Error("many", f, ok)
+	}
+}
+
+func TestNewResolvesLanguage(t *testing.T) {
+	r := NewRegistry()
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		return pack
+	}, DefaultPriority, language.English)
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		return polishFiles
+	}, DefaultPriority, language.Polish)
+
+	tf := NewFinder(r, "en-US")
+	if tf.Language() != language.English {
+		t.Error("en-US", tf.Language())
+	}
+
+	if s := tf.Text(Hello); s != "Hello World" {
+		t.Error("en-US text", s)
+	}
+
+	tf = NewFinder(r, "pl-PL")
+	if tf.Language() != language.Polish {
+		t.Error("pl-PL", tf.Language())
+	}
+
+	tf = NewFinder(r, "ja")
+	if tf.Language() != language.English {
+		t.Error("ja", tf.Language())
+	}
+}
+
+func TestNewUnregisteredUsesRequestedLanguage(t *testing.T) {
+	tf := NewFinder(NewRegistry(), "pl", polishFiles)
+	if tf.Language() != language.Polish {
+		t.Error("pl", tf.Language())
+	}
+}
+
+func TestCtxSprintfCount(t *testing.T) {
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.Polish, polishFiles))
+
+	s := CtxSprintfCount(ctx, Hello, 5, 5)
+	if s != "5 plików" {
+		t.Error("Mismatch ", s)
+	}
+
+	s = CtxSprintfCount(ctx, Args, 5, 5)
+	if s != "3:5" {
+		t.Error("Mismatch ", s)
+	}
+}
+
+func TestSprintfCount(t *testing.T) {
+	s := SprintfCount(Args, 2, 2)
+	if s != "Plurals 2" {
+		t.Error("Mismatch ", s)
+	}
+
+	s = SprintfCount(Args, 1, 1)
+	if s != "Single 1" {
+		t.Error("Mismatch ", s)
+	}
+
+	if _, found := FindCount(Default(), Args, minInt); !found {
+		t.Error("min int")
+	}
+}
+
+func TestOrdinalCategoryOf(t *testing.T) {
+	tests := []struct {
+		lang     string
+		n        int
+		category PluralCategory
+	}{
+		{"en", 1, PluralOne},
+		{"en", 2, PluralTwo},
+		{"en", 3, PluralFew},
+		{"en", 4, PluralOther},
+		{"en", 11, PluralOther},
+		{"en", 23, PluralFew},
+		{"fr", 1, PluralOne},
+		{"fr", 2, PluralOther},
+		{"de", 1, PluralOther},
+		{"en", 10000001, PluralOne},
+		{"en", 10000011, PluralOther},
+		{"en", 20000003, PluralFew},
+		{"en", minInt, PluralOther},
+	}
+
+	for _, test := range tests {
+		c := OrdinalCategoryOf(language.MustParse(test.lang), test.n)
+		if c != test.category {
+			t.Error(test.lang, test.n, c, "expected", test.category)
+		}
+	}
+}
+
+func TestByOrdinal(t *testing.T) {
+	id := ByOrdinal(Hello, language.English, 22)
+	if id != ByOrdinalCategory(Hello, PluralTwo) {
+		t.Error("22nd", id)
+	}
+
+	if id == ByCategory(Hello, PluralTwo) {
+		t.Error("ordinal matches cardinal")
+	}
+
+	if s := id.String(); s != "2[ordinal-two]" {
+		t.Error("String", s)
+	}
+}
+
+var englishPlaces = TextMap{
+	Hello:                                 "%d place",
+	ByOrdinalCategory(Hello, PluralOne):   "%dst place",
+	ByOrdinalCategory(Hello, PluralTwo):   "%dnd place",
+	ByOrdinalCategory(Hello, PluralFew):   "%drd place",
+	ByOrdinalCategory(Hello, PluralOther): "%dth place",
+	ByOrdinalCategory(Args, PluralOne):    "%dst retry",
+}
+
+func TestFindOrdinal(t *testing.T) {
+	tf := NewLanguageFinder(language.English, englishPlaces)
+
+	for n, expected := range map[int]string{
+		1:  "%dst place",
+		2:  "%dnd place",
+		23: "%drd place",
+		11: "%dth place",
+	} {
+		f, ok := FindOrdinal(tf, Hello, n)
+		if !ok || f != expected {
+			t.Error(n, f, ok)
+		}
+	}
+
+	if _, ok := FindOrdinal(tf, Args, 2); ok {
+		t.Error("found missing")
+	}
+}
+
+func TestFindOrdinalFallsBack(t *testing.T) {
+	tf := NewLanguageFinder(language.German, TextMap{Hello: "%d. Platz"})
+
+	f, ok := FindOrdinal(tf, Hello, 3)
+	if !ok || f != "%d. Platz" {
+		t.Error("fallback", f, ok)
+	}
+}
+
+func TestCtxSprintfOrdinal(t *testing.T) {
+	ctx := WithContext(context.Background(), NewLanguageFinder(language.English, englishPlaces))
+
+	s := CtxSprintfOrdinal(ctx, Hello, 23, 23)
+	if s != "23rd place" {
+		t.Error("Mismatch ", s)
+	}
+
+	s = CtxSprintfOrdinal(ctx, None, 2, 2)
+	if s != "1:2" {
+		t.Error("Mismatch ", s)
+	}
+}
+
+func TestSprintfOrdinal(t *testing.T) {
+	s := SprintfOrdinal(Args, 2, 2)
+	if s != "Single 2" {
+		t.Error("Mismatch ", s)
+	}
+}
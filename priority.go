@@ -16,6 +16,8 @@ limitations under the License.
 
 package lpax
 
+import "fmt"
+
 // Priority type to specify pack registration priority
 // Priority is used by Text Registration types to prioritize their
 // stored items.
@@ -45,3 +47,21 @@ const (
 	// DefaultPriority default type priority.
 	DefaultPriority = Package
 )
+
+// String returns the name of the priority's constant.
+func (p Priority) String() string {
+	switch p {
+	case AdditionalPacks:
+		return "AdditionalPacks"
+	case Package:
+		return "Package"
+	case Override:
+		return "Override"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// MarshalText encodes the priority as its name.
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
@@ -30,3 +30,20 @@ func TestPriorityRanking(t *testing.T) {
 		t.Error("DefaultPriority != Package", DefaultPriority, Package)
 	}
 }
+
+func TestPriorityString(t *testing.T) {
+	for p, want := range map[Priority]string{
+		AdditionalPacks: "AdditionalPacks",
+		Package:         "Package",
+		Override:        "Override",
+		Priority(7):     "Priority(7)",
+	} {
+		if s := p.String(); s != want {
+			t.Error(p, s)
+		}
+	}
+
+	if b, err := Override.MarshalText(); err != nil || string(b) != "Override" {
+		t.Error("marshal", string(b), err)
+	}
+}
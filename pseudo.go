@@ -0,0 +1,228 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nehemming/lpax/internal/printf"
+	"golang.org/x/text/language"
+)
+
+var (
+	// PseudoAccented is the en-XA pseudo-locale, the default language text with accented letters,
+	// expanded by about 40% and bracketed, e.g. [Ĥéļļö Ŵöŕļð one two], revealing hard coded strings,
+	// truncation and concatenated text.
+	PseudoAccented = language.MustParse("en-XA")
+
+	// PseudoBidi is the ar-XB pseudo-locale, the default language text with each word forced right to
+	// left, testing mirrored layouts using text that remains readable.
+	PseudoBidi = language.MustParse("ar-XB")
+)
+
+const (
+	rlm = "\u200f" // right to left mark
+	rlo = "\u202e" // right to left override
+	pdf = "\u202c" // pop directional formatting
+)
+
+// accents maps ASCII letters to accented versions.
+var accents = map[rune]rune{
+	'a': 'å', 'b': 'ƀ', 'c': 'ç', 'd': 'ð', 'e': 'é', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ĥ', 'i': 'î',
+	'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ɱ', 'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ',
+	's': 'š', 't': 'ţ', 'u': 'û', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î',
+	'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ', 'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ',
+	'S': 'Š', 'T': 'Ţ', 'U': 'Û', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+// expansion are the words appended to expand PseudoAccented text.
+var expansion = strings.Fields("one two three four five six seven eight nine ten")
+
+// IsPseudoLocale reports if the tag is PseudoAccented or PseudoBidi.
+func IsPseudoLocale(langTag Tag) bool {
+	return langTag == PseudoAccented || langTag == PseudoBidi
+}
+
+// Pseudolocalize returns the pseudo-locale version of s for PseudoAccented or PseudoBidi, any other tag
+// returns s unchanged.  fmt verbs, {placeholder} names and the argument syntax of ICU MessageFormat
+// patterns are not changed, braces in text that is not a MessageFormat pattern are literal text.
+func Pseudolocalize(langTag Tag, s string) string {
+	if !IsPseudoLocale(langTag) || s == "" {
+		return s
+	}
+
+	p := &pseudolocalizer{bidi: langTag == PseudoBidi}
+	p.transform(s)
+
+	if p.bidi {
+		return p.out.String()
+	}
+
+	return "[" + p.out.String() + p.padding() + "]"
+}
+
+// pseudolocalizer writes the pseudo-locale version of text.
+type pseudolocalizer struct {
+	bidi    bool
+	out     strings.Builder
+	word    []rune
+	letters int
+}
+
+// transform writes the pseudo-locale version of s.  In text that parses as a MessageFormat pattern, text
+// within braces is an argument, such as {name} or {count, plural, one {# file} other {# files}}, whose
+// syntax is copied and whose nested messages are transformed, and apostrophe quoted text, such as '{',
+// is literal text.  Braces in other text, such as fmt format strings, are literal text.
+func (p *pseudolocalizer) transform(s string) {
+	_, err := cachedMessage(s)
+	icu := err == nil
+
+	// inArg records, for each nested brace, if it opened an argument rather than a nested message
+	var inArg []bool
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		argument := len(inArg) > 0 && inArg[len(inArg)-1]
+
+		switch {
+		case icu && r == '{':
+			p.flush()
+			inArg = append(inArg, !argument)
+			p.out.WriteRune(r)
+
+		case icu && r == '}' && len(inArg) > 0:
+			p.flush()
+			inArg = inArg[:len(inArg)-1]
+			p.out.WriteRune(r)
+
+		case argument:
+			p.out.WriteRune(r)
+
+		case icu && r == '\'':
+			p.flush()
+			size = p.quoted(s[i:])
+
+		case r == '%':
+			p.flush()
+			end := printf.ParseVerb(s, i, 0).End
+			p.out.WriteString(s[i:end])
+			size = end - i
+
+		case unicode.IsLetter(r):
+			p.word = append(p.word, r)
+
+		default:
+			p.flush()
+			p.out.WriteRune(r)
+		}
+
+		i += size
+	}
+
+	p.flush()
+}
+
+// quoted writes the pseudo-locale version of the MessageFormat apostrophe at the start of s, and of the
+// text it quotes, returning the length of s written.  A doubled apostrophe is a literal apostrophe and an
+// apostrophe before a syntax character quotes the text up to the next single apostrophe.
+func (p *pseudolocalizer) quoted(s string) int {
+	if len(s) == 1 || strings.IndexByte("{}|'", s[1]) < 0 {
+		p.out.WriteByte('\'')
+		return 1
+	}
+
+	if s[1] == '\'' {
+		p.out.WriteString("''")
+		return 2
+	}
+
+	p.out.WriteByte('\'')
+
+	i := 1
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case strings.HasPrefix(s[i:], "''"):
+			p.flush()
+			p.out.WriteString("''")
+			size = 2
+
+		case r == '\'':
+			p.flush()
+			p.out.WriteByte('\'')
+			return i + 1
+
+		case unicode.IsLetter(r):
+			p.word = append(p.word, r)
+
+		default:
+			p.flush()
+			p.out.WriteRune(r)
+		}
+
+		i += size
+	}
+
+	p.flush()
+	return i
+}
+
+// flush writes the current word.
+func (p *pseudolocalizer) flush() {
+	if len(p.word) == 0 {
+		return
+	}
+
+	p.letters += len(p.word)
+
+	if p.bidi {
+		p.out.WriteString(rlm + rlo + string(p.word) + pdf + rlm)
+	} else {
+		for _, r := range p.word {
+			if a, ok := accents[r]; ok {
+				r = a
+			}
+			p.out.WriteRune(r)
+		}
+	}
+
+	p.word = p.word[:0]
+}
+
+// padding returns the expansion words adding about 40% to the number of letters.
+func (p *pseudolocalizer) padding() string {
+	target := (p.letters*4 + 9) / 10
+
+	var b strings.Builder
+	for i := 0; b.Len() < target; i++ {
+		b.WriteString(" " + expansion[i%len(expansion)])
+	}
+	return b.String()
+}
+
+// pseudoTextMap returns the pseudo-locale version of the text map.
+func pseudoTextMap(langTag Tag, tm TextMap) TextMap {
+	pseudo := make(TextMap, len(tm))
+	for k, v := range tm {
+		pseudo[k] = Pseudolocalize(langTag, v)
+	}
+	return pseudo
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestPseudolocalizeAccented(t *testing.T) {
+	for s, want := range map[string]string{
+		"":                      "",
+		"Hello World":           "[Ĥéļļö Ŵöŕļð one]",
+		"%d files in %-8.2[2]s": "[%d ƒîļéš îñ %-8.2[2]s one]",
+		"100%% of {name}":       "[100%% öƒ {name} one]",
+		"%":                     "[%]",
+		"{n, plural, one {# file} other {# files}}": "[{n, plural, one {# ƒîļé} other {# ƒîļéš}} one]",
+		"Use '{name}' literally {n}":                "[Ûšé '{ñåɱé}' ļîţéŕåļļý {n} one two]",
+		"It''s {n}":                                 "[Îţ''š {n} one]",
+		"'{'{n}'}'":                                 "['{'{n}'}']",
+		"{not an argument} %d {":                    "[{ñöţ åñ åŕĝûɱéñţ} %d { one two]",
+		"Set {%d":                                   "[Šéţ {%d one]",
+	} {
+		if got := Pseudolocalize(PseudoAccented, s); got != want {
+			t.Errorf("%q got %q", s, got)
+		}
+	}
+
+	if s := Pseudolocalize(language.English, "Hello"); s != "Hello" {
+		t.Error("english", s)
+	}
+}
+
+func TestPseudolocalizeBidi(t *testing.T) {
+	got := Pseudolocalize(PseudoBidi, "Hello %s!")
+	want := "\u200f\u202eHello\u202c\u200f %s!"
+
+	if got != want {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPseudolocalizePreservesVerbs(t *testing.T) {
+	for _, tag := range []Tag{PseudoAccented, PseudoBidi} {
+		s := Pseudolocalize(tag, "%[2]v has %[1]d")
+		if got := fmt.Sprintf(s, 3, "x"); strings.Contains(got, "%!") {
+			t.Error(tag, got)
+		}
+	}
+}
+
+func TestPseudoLocaleFinder(t *testing.T) {
+	r, _ := newMissingRegistry()
+
+	tf := NewFinder(r, PseudoAccented)
+	if s := tf.Text(Hello); s != "[Ĥéļļö Ŵöŕļð one]" {
+		t.Error("accented", s)
+	}
+
+	if lf, ok := tf.(LanguageFinder); !ok || lf.Language() != PseudoAccented {
+		t.Error("language")
+	}
+
+	ctx := WithContext(context.Background(), NewFinder(r, PseudoBidi, TextMap{Args: "%d args"}))
+	if s := CtxSprintf(ctx, Args, 2); s != "2 \u200f\u202eargs\u202c\u200f" {
+		t.Errorf("bidi %q", s)
+	}
+
+	// registered pseudo-locales are used as is
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		return TextMap{Hello: "registered"}
+	}, Package, PseudoAccented)
+
+	if s := NewFinder(r, PseudoAccented).Text(Hello); s != "registered" {
+		t.Error("registered", s)
+	}
+
+	if IsPseudoLocale(language.English) || !IsPseudoLocale(PseudoBidi) {
+		t.Error("IsPseudoLocale")
+	}
+}
@@ -18,8 +18,9 @@ package lpax
 
 import (
 	"fmt"
-	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/text/language"
 )
@@ -27,7 +28,8 @@ import (
 // OnRegister is the a callback function signature used to locate a registered
 // language pack and return a TextMap containing its contents.
 // The callback will only be called for pack IDs and languages that were registered with the registry.
-// OnRegister may be called each time Registry New is called.
+// OnRegister may be called each time Registry New is called with languages not already cached
+// since the last registration.
 type OnRegister = func(packID PackID, langTag Tag) TextMap
 
 // TextRegistry maintains a list of registered resource TextMaps by language.
@@ -45,9 +47,10 @@ type TextRegistry interface {
 	Register(packID PackID, callback OnRegister, priority Priority, langTags ...Tag) TextRegistry
 
 	// New returns a new text finder created from the registry.  Each call creates a new finder
-	// options can be language Tags and additional TextMaps
+	// options can be language Tags, additional TextMaps and a MissingPolicy
 	// language Tags must be supplied with the fallback language being first language in the list, if no language is provided the
-	// DefaultLanguage is used.
+	// DefaultLanguage is used.  If the first tag is the PseudoAccented or PseudoBidi pseudo-locale, and no pack registers it,
+	// the finder's text is synthesized from the DefaultLanguage text.
 	New(options ...interface{}) TextFinder
 }
 
@@ -56,6 +59,7 @@ type (
 		priority  Priority
 		callback  OnRegister
 		supported []Tag
+		owner     interface{} // identifies a registration whose languages may be replaced, nil if fixed
 	}
 
 	packEntries []packEntry
@@ -67,16 +71,58 @@ type (
 
 	packEntryMap map[PackID]*packGroup
 
+	// finderKey is the comparable cache key of the language tags passed to New.
+	finderKey struct {
+		n    int
+		tags [maxCachedTags]Tag
+	}
+
+	// packMatcher matches requested languages to the distinct languages registered for a pack.
+	packMatcher struct {
+		packID  PackID
+		keys    []Tag
+		matcher language.Matcher
+	}
+
+	// registryMatcher matches requested languages to the languages registered for each pack and
+	// across all packs, it is rebuilt after each registration.
+	registryMatcher struct {
+		sequence uint64
+		packs    []packMatcher
+		all      []Tag
+		matcher  language.Matcher // nil if nothing is registered
+	}
+
+	// providerSnapshot is the shared runtime provider and the registration sequence it was built from.
+	providerSnapshot struct {
+		textMap  *languageTextMap
+		sequence uint64
+	}
+
 	packRegistry struct {
-		registered  packEntryMap
-		muInit      sync.Mutex // lock called during pack runtime shared initProvider calls
-		mu          sync.Mutex // lock on internal structures
-		regSequence int
-		proSequence int
-		textMap     TextMap
+		regSequence    uint64 // accessed atomically, first to guarantee 64 bit alignment
+		registered     packEntryMap
+		muInit         sync.Mutex   // lock called during pack runtime shared initProvider calls
+		mu             sync.RWMutex // lock on internal structures
+		provider       atomic.Value // *providerSnapshot swapped by initProvider
+		finders        map[finderKey]*languageTextMap
+		resolved       map[string]*languageTextMap // finders keyed by the languages resolved for each pack
+		finderSequence uint64
+		matcher        atomic.Value // *registryMatcher
+		missing        *missingHandler
+		fallback       FallbackChain
 	}
 )
 
+const (
+	// maxCachedTags is the maximum number of language tags passed to New that are cached without
+	// resolving them, New calls with more tags share the finders cached by resolved language.
+	maxCachedTags = 4
+
+	// maxCachedFinders limits the number of finders cached by New, a cache is cleared when full.
+	maxCachedFinders = 1024
+)
+
 var (
 	once                sync.Once
 	defaultTextRegistry TextRegistry
@@ -94,6 +140,10 @@ func Default() TextRegistry {
 func NewRegistry() TextRegistry {
 	return &packRegistry{
 		registered: make(packEntryMap),
+		finders:    make(map[finderKey]*languageTextMap),
+		resolved:   make(map[string]*languageTextMap),
+		missing:    newMissingHandler(),
+		fallback:   CLDRFallback,
 	}
 }
 
@@ -107,6 +157,11 @@ func (r *packRegistry) Find(textID TextID) (t string, found bool) {
 	return r.initTextProvider().Find(textID)
 }
 
+// Language returns the language tag resolved for the process owners detected language.
+func (r *packRegistry) Language() Tag {
+	return r.initTextProvider().Language()
+}
+
 // Register adds a new registration resource for a pack ID and range of languages.
 func (r *packRegistry) Register(packID PackID, callback OnRegister, priority Priority, langTags ...Tag) TextRegistry {
 	// Check for case where nothing is registered
@@ -142,20 +197,269 @@ func (r *packRegistry) Register(packID PackID, callback OnRegister, priority Pri
 		group.isSorted = false
 	}
 
-	r.regSequence++
+	atomic.AddUint64(&r.regSequence, 1)
 
 	return r
 }
 
-// New creates a new provider.
+// languageRegistrar is implemented by registries able to replace the languages of a registration,
+// allowing a Watcher to unregister the languages whose files have been removed.
+type languageRegistrar interface {
+	// registerLanguages registers the pack for langTags in place of the languages previously registered
+	// by the owner, a registration without languages is removed.
+	registerLanguages(owner interface{}, packID PackID, callback OnRegister, priority Priority, langTags []Tag)
+}
+
+// registerLanguages replaces the languages registered by the owner.
+func (r *packRegistry) registerLanguages(owner interface{}, packID PackID, callback OnRegister, priority Priority, langTags []Tag) {
+	validateTextID(packID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, found := r.registered[packID]
+	if !found {
+		group = newPackGroup()
+	}
+
+	replacement := packEntry{
+		priority:  priority,
+		callback:  callback,
+		supported: append([]Tag(nil), langTags...),
+		owner:     owner,
+	}
+
+	// replace the owner's entry in place, dropping it if it has no languages
+	entries := make(packEntries, 0, len(group.entries)+1)
+	replaced := false
+	for _, entry := range group.entries {
+		if entry.owner != owner {
+			entries = append(entries, entry)
+			continue
+		}
+
+		replaced = true
+		if len(langTags) > 0 {
+			entries = append(entries, replacement)
+		}
+	}
+
+	if !replaced && len(langTags) > 0 {
+		entries = append(entries, replacement)
+	}
+
+	group.entries, group.isSorted = entries, false
+
+	if len(entries) == 0 {
+		delete(r.registered, packID)
+	} else {
+		r.registered[packID] = group
+	}
+
+	atomic.AddUint64(&r.regSequence, 1)
+}
+
+// RefreshRegistry is implemented by registries caching the text returned by their callbacks, such as those
+// created by NewRegistry.
+type RefreshRegistry interface {
+	// Refresh discards the cached finders and shared provider so the registered callbacks are called
+	// again, used when the text returned by a callback has changed.
+	Refresh()
+}
+
+// Refresh invalidates the finders cached by New and the shared provider.
+func (r *packRegistry) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	atomic.AddUint64(&r.regSequence, 1)
+}
+
+// sequence returns the registry's sequence, changed by each registration, Refresh and SetFallback.
+func (r *packRegistry) sequence() uint64 {
+	return atomic.LoadUint64(&r.regSequence)
+}
+
+// FinderRegistry is implemented by registries able to create LanguageFinders, see NewFinder.
+type FinderRegistry interface {
+	// NewFinder returns a LanguageFinder created from the registry for New's options.
+	NewFinder(options ...interface{}) LanguageFinder
+}
+
+// NewFinder returns a LanguageFinder created from the registry r for New's options.  Unlike the TextMap
+// returned by New the finder knows its language, used to select CLDR plural variants, reports the
+// language supplying each text as a SourceFinder and applies the registry's MissingPolicy.
+// Registries that do not implement FinderRegistry have the finder returned by New wrapped
+// with the first language passed, or the DefaultLanguage.
+func NewFinder(r TextRegistry, options ...interface{}) LanguageFinder {
+	if fr, ok := r.(FinderRegistry); ok {
+		return fr.NewFinder(options...)
+	}
+
+	tf := r.New(options...)
+	if lf, ok := tf.(LanguageFinder); ok {
+		return lf
+	}
+
+	return &taggedFinder{TextFinder: tf, langTag: firstTag(options)}
+}
+
+// firstTag returns the first language tag of New's options, or the DefaultLanguage if there is none.
+func firstTag(options []interface{}) Tag {
+	for _, o := range options {
+		switch v := o.(type) {
+		case Tag:
+			return v
+		case string:
+			return language.MustParse(v)
+		}
+	}
+
+	return language.MustParse(DefaultLanguage)
+}
+
+// taggedFinder is a TextFinder of a known language.
+type taggedFinder struct {
+	TextFinder
+	langTag Tag
+}
+
+// Language returns the language of the finder.
+func (tf *taggedFinder) Language() Tag {
+	return tf.langTag
+}
+
+// New creates a new provider, a TextMap of the registered text for the options.  The TextMap is
+// shared by the callers passing the same languages and must not be modified.
 func (r *packRegistry) New(options ...interface{}) TextFinder {
-	return r.newTextMap(options...)
+	return r.newFinder(options).TextMap
+}
+
+// NewFinder creates a new LanguageFinder for New's options.
+func (r *packRegistry) NewFinder(options ...interface{}) LanguageFinder {
+	return r.newFinder(options)
+}
+
+// newFinder creates the finder of New and NewFinder.
+// Finders created from language tags alone are cached until the next registration.  The cache is
+// keyed by the registered languages the tags resolve to, so requests for different tags, such as
+// browser Accept-Language lists, resolving to the same languages share a finder.
+func (r *packRegistry) newFinder(options []interface{}) *languageTextMap {
+	key, keyed := newFinderKey(options)
+	if keyed {
+		r.mu.RLock()
+		tm, found := r.finders[key]
+		current := r.finderSequence == atomic.LoadUint64(&r.regSequence)
+		r.mu.RUnlock()
+
+		if found && current {
+			return tm
+		}
+	}
+
+	langTag, cacheable := finderTags(options)
+	if !cacheable {
+		return r.newLanguageTextMap(options...)
+	}
+
+	m := r.currentMatcher()
+	resolved := m.key(langTag)
+
+	r.mu.RLock()
+	tm, found := r.resolved[resolved]
+	current := r.finderSequence == m.sequence
+	r.mu.RUnlock()
+
+	if !found || !current {
+		tm = r.newLanguageTextMap(options...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// only cache if nothing has been registered while the provider was created
+	if m.sequence != atomic.LoadUint64(&r.regSequence) {
+		return tm
+	}
+
+	if r.finderSequence != m.sequence {
+		r.finders = make(map[finderKey]*languageTextMap)
+		r.resolved = make(map[string]*languageTextMap)
+		r.finderSequence = m.sequence
+	}
+
+	if keyed {
+		if len(r.finders) >= maxCachedFinders {
+			r.finders = make(map[finderKey]*languageTextMap)
+		}
+		r.finders[key] = tm
+	}
+
+	if len(r.resolved) >= maxCachedFinders {
+		r.resolved = make(map[string]*languageTextMap)
+	}
+	r.resolved[resolved] = tm
+
+	return tm
+}
+
+// newFinderKey creates the cache key for New's options.
+// Options containing TextMaps or more than maxCachedTags tags have no key.
+func newFinderKey(options []interface{}) (finderKey, bool) {
+	var key finderKey
+
+	for _, o := range options {
+		if key.n == maxCachedTags {
+			return key, false
+		}
+
+		switch v := o.(type) {
+		case Tag:
+			key.tags[key.n] = v
+		case string:
+			key.tags[key.n] = language.MustParse(v)
+		default:
+			return key, false
+		}
+
+		key.n++
+	}
+
+	return key, true
+}
+
+// finderTags returns the language tags of New's options, defaulting to the DefaultLanguage.
+// Options other than tags are not cacheable.
+func finderTags(options []interface{}) ([]Tag, bool) {
+	langTag := make([]Tag, 0, len(options))
+
+	for _, o := range options {
+		switch v := o.(type) {
+		case Tag:
+			langTag = append(langTag, v)
+		case string:
+			langTag = append(langTag, language.MustParse(v))
+		default:
+			return nil, false
+		}
+	}
+
+	if len(langTag) == 0 {
+		langTag = append(langTag, language.MustParse(DefaultLanguage))
+	}
+
+	return langTag, true
 }
 
 func (r *packRegistry) newTextMap(options ...interface{}) TextMap {
+	return r.newLanguageTextMap(options...).TextMap
+}
+
+func (r *packRegistry) newLanguageTextMap(options ...interface{}) *languageTextMap {
 	// resolve options
 	langTag := make([]Tag, 0, len(options))
 	textMaps := make([]TextMap, 0)
+	var policy *MissingPolicy
 	for _, o := range options {
 		switch v := o.(type) {
 		case Tag:
@@ -166,6 +470,8 @@ func (r *packRegistry) newTextMap(options ...interface{}) TextMap {
 			textMaps = append(textMaps, v)
 		case []TextMap:
 			textMaps = append(textMaps, v...)
+		case MissingPolicy:
+			policy = &v
 		default:
 			// developer issuer passing wrong type
 			panic(fmt.Sprintf("Invalid %[1]T %[1]v", o))
@@ -178,9 +484,52 @@ func (r *packRegistry) newTextMap(options ...interface{}) TextMap {
 	}
 
 	// Gather all the text mappings
-	textMap := r.getLanguageTextMap(langTag...)
+	textMap, sources, matchTag := r.getLanguageTextMap(langTag...)
+	textMap = textMap.Merge(textMaps...)
 
-	return textMap.Merge(textMaps...)
+	// text passed to New is reported as the finder's language
+	for _, m := range textMaps {
+		for k := range m {
+			delete(sources, k)
+		}
+	}
+
+	// synthesize pseudo-locales that are not registered from the default language
+	if IsPseudoLocale(langTag[0]) && matchTag != langTag[0] {
+		textMap, _, _ = r.getLanguageTextMap(language.MustParse(DefaultLanguage))
+		textMap = pseudoTextMap(langTag[0], textMap.Merge(textMaps...))
+		sources, matchTag = nil, langTag[0]
+	}
+
+	tm := newLanguageTextMap(matchTag, textMap)
+	tm.sources, tm.missing, tm.policy = sources, r.missing, policy
+
+	return tm
+}
+
+// SetMissingPolicy sets how the finders created by the registry handle text missing when rendered.
+func (r *packRegistry) SetMissingPolicy(policy MissingPolicy) {
+	r.missing.policy.Store(policy)
+}
+
+// Misses returns the number of times the text of each TextID has been missing when rendered.
+func (r *packRegistry) Misses() map[TextID]int {
+	return r.missing.misses()
+}
+
+// ResetMisses clears the counts returned by Misses.
+func (r *packRegistry) ResetMisses() {
+	r.missing.reset()
+}
+
+// reportMissing reports that the text of id was missing from the shared provider when rendered.
+func (r *packRegistry) reportMissing(id TextID) MissingMode {
+	return r.initTextProvider().reportMissing(id)
+}
+
+// missingMode returns the mode of the registry's policy.
+func (r *packRegistry) missingMode() MissingMode {
+	return r.missing.current(nil).Mode
 }
 
 // newPackGroup creates a new pack group to store language pack registrations.
@@ -190,80 +539,170 @@ func newPackGroup(entries ...packEntry) *packGroup {
 	}
 }
 
-func (r *packRegistry) getLanguageTextMap(langTag ...Tag) TextMap {
+// getLanguageTextMap merges the best matching registered text for each pack, falling back key by key
+// along the registry's FallbackChain, and returns it along with the language that supplied each text
+// and the overall best matching registered language.
+func (r *packRegistry) getLanguageTextMap(langTag ...Tag) (TextMap, map[TextID]Tag, Tag) {
 	// Lock
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// List of text maps
-	textMaps := make([]TextMap, 0, len(r.registered))
+	m := r.matcherLocked()
 
-	for packID, group := range r.registered {
-		if !group.isSorted {
-			sort.Sort(group.entries)
-			group.isSorted = true
+	textMap := make(TextMap)
+	sources := make(map[TextID]Tag)
+
+	for _, p := range m.packs {
+		entries := r.registered[p.packID].entries
+		chain := r.fallbackChain(p.match(langTag))
+
+		// merge the end of the chain first so text of the closer languages overrides it
+		for j := len(chain) - 1; j >= 0; j-- {
+			tag := chain[j]
+
+			for _, entry := range entries {
+				// skip calling if the callback didn't register the tag
+				if !entry.supports(tag) {
+					continue
+				}
+
+				tm := entry.callback(p.packID, tag)
+
+				// may be overridden by a later match, continue to search
+				textMap.Merge(tm)
+				for k := range tm {
+					sources[k] = tag
+				}
+			}
 		}
+	}
 
-		entries := group.entries
+	return textMap, sources, m.language(langTag)
+}
 
-		// generate a distinct set of supported keys
-		distinct := make(map[Tag]bool)
-		entryCount := len(entries)
-		keys := make([]Tag, 0, entryCount) // guess 1 key per entryCount
+// currentMatcher returns the matcher of the current registrations.
+func (r *packRegistry) currentMatcher() *registryMatcher {
+	if m, ok := r.matcher.Load().(*registryMatcher); ok && m.sequence == atomic.LoadUint64(&r.regSequence) {
+		return m
+	}
 
-		// gather the callbacks into a list at the same time
-		callbacks := make([]OnRegister, 0, entryCount)
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		// callbackFilter links the callback to the supported languages it was registered to provide
-		callbackFilter := make([]map[Tag]bool, entryCount)
-		for i, entry := range entries {
-			// Add in callback
-			callbacks = append(callbacks, entry.callback)
+	return r.matcherLocked()
+}
 
-			// Create the filter
-			callbackFilter[i] = make(map[Tag]bool)
+// matcherLocked returns the matcher of the current registrations, building it if a registration has
+// been made since it was built.  The write lock must be held.
+func (r *packRegistry) matcherLocked() *registryMatcher {
+	sequence := atomic.LoadUint64(&r.regSequence)
+	if m, ok := r.matcher.Load().(*registryMatcher); ok && m.sequence == sequence {
+		return m
+	}
 
-			// get distinct keys
-			for _, tag := range entry.supported {
-				callbackFilter[i][tag] = true
+	m := &registryMatcher{sequence: sequence, packs: make([]packMatcher, 0, len(r.registered))}
+
+	// all the distinct tags registered across the packs
+	allDistinct := make(map[Tag]bool)
+
+	for packID, group := range r.registered {
+		group.sort()
 
+		// generate a distinct set of supported keys
+		distinct := make(map[Tag]bool)
+		keys := make([]Tag, 0, len(group.entries)) // guess 1 key per entry
+
+		for _, entry := range group.entries {
+			for _, tag := range entry.supported {
 				if distinct[tag] {
 					continue // ignore as already in use
 				}
 
 				distinct[tag] = true
 				keys = append(keys, tag)
+
+				if !allDistinct[tag] {
+					allDistinct[tag] = true
+					m.all = append(m.all, tag)
+				}
 			}
 		}
 
-		// Find best match
-		m := language.NewMatcher(keys)
-		matchTag, _, _ := m.Match(langTag...)
+		m.packs = append(m.packs, packMatcher{packID: packID, keys: keys, matcher: language.NewMatcher(keys)})
+	}
 
-		for i, callback := range callbacks {
-			// skip calling if the callback didn't register the tag
-			if !callbackFilter[i][matchTag] {
-				continue
-			}
+	if len(m.all) > 0 {
+		m.matcher = language.NewMatcher(m.all)
+	}
 
-			tm := callback(packID, matchTag)
+	r.matcher.Store(m)
 
-			if tm != nil {
-				textMaps = append(textMaps, tm)
-				// may be overridden by a later match, continue to search
-			}
-		}
+	return m
+}
+
+// match returns the registered tag best matching the requested tags, the matched tag may carry
+// extensions so the registered tag is used.
+func (p *packMatcher) match(langTag []Tag) Tag {
+	_, index, _ := p.matcher.Match(langTag...)
+	return p.keys[index]
+}
+
+// language returns the registered tag best matching the requested tags, see matchLanguage.
+func (m *registryMatcher) language(langTag []Tag) Tag {
+	if m.matcher == nil {
+		return langTag[0]
+	}
+	return matchWith(m.matcher, m.all, langTag)
+}
+
+// key returns the cache key of the finder created for the requested tags, the finder's language
+// followed by the language resolved for each pack.
+func (m *registryMatcher) key(langTag []Tag) string {
+	lang := m.language(langTag)
+
+	// unregistered pseudo-locales are synthesized
+	if IsPseudoLocale(langTag[0]) && lang != langTag[0] {
+		lang = langTag[0]
 	}
 
-	return NewTextMap(textMaps...)
+	var b strings.Builder
+	b.WriteString(lang.String())
+
+	for i := range m.packs {
+		b.WriteByte(' ')
+		b.WriteString(m.packs[i].match(langTag).String())
+	}
+
+	return b.String()
 }
 
-// initTextProvider is used to initialism a provider.
-func (r *packRegistry) initTextProvider() TextMap {
-	isCurrent := r.proSequence >= r.regSequence
-	tm := r.textMap
+// matchLanguage returns the registered tag best matching the requested tags.
+// If nothing is registered the first requested tag is returned and
+// if nothing matches the DefaultLanguage is returned.
+func matchLanguage(registered []Tag, langTag []Tag) Tag {
+	if len(registered) == 0 {
+		return langTag[0]
+	}
+
+	return matchWith(language.NewMatcher(registered), registered, langTag)
+}
+
+// matchWith returns the registered tag best matching the requested tags using a matcher of the
+// registered tags, or the DefaultLanguage if nothing matches.
+func matchWith(m language.Matcher, registered []Tag, langTag []Tag) Tag {
+	_, index, confidence := m.Match(langTag...)
+	if confidence == language.No {
+		return language.MustParse(DefaultLanguage)
+	}
 
-	if tm != nil && isCurrent {
+	return registered[index]
+}
+
+// initTextProvider is used to initialism a provider.
+// Readers load the current snapshot without locking, the snapshot is rebuilt and swapped
+// atomically when a registration has been made since it was built.
+func (r *packRegistry) initTextProvider() *languageTextMap {
+	if tm, ok := r.currentProvider(); ok {
 		return tm
 	}
 
@@ -271,10 +710,27 @@ func (r *packRegistry) initTextProvider() TextMap {
 	r.muInit.Lock()
 	defer r.muInit.Unlock()
 
+	// another caller may have rebuilt the provider while waiting for the lock
+	if tm, ok := r.currentProvider(); ok {
+		return tm
+	}
+
+	// registrations made while building will cause a further rebuild
+	sequence := atomic.LoadUint64(&r.regSequence)
+
 	tag := MustDetectLocaleLanguage(DefaultLanguage)
 
-	r.textMap = r.newTextMap(tag)
-	r.proSequence = r.regSequence
+	tm := r.newLanguageTextMap(tag)
+	r.provider.Store(&providerSnapshot{textMap: tm, sequence: sequence})
 
-	return r.textMap
+	return tm
+}
+
+// currentProvider returns the provider snapshot if it is up to date with the registrations.
+func (r *packRegistry) currentProvider() (*languageTextMap, bool) {
+	snapshot, ok := r.provider.Load().(*providerSnapshot)
+	if !ok || snapshot.sequence != atomic.LoadUint64(&r.regSequence) {
+		return nil, false
+	}
+	return snapshot.textMap, true
 }
@@ -18,7 +18,11 @@ package lpax
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
+
+	"golang.org/x/text/language"
 )
 
 func TestNewRegistry(t *testing.T) {
@@ -107,3 +111,221 @@ func TestNewTextMapBadArgPanics(t *testing.T) {
 
 	r.newTextMap(10.7)
 }
+
+func newCountingRegistry(calls *int) TextRegistry {
+	return NewRegistry().Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		*calls++
+		return pack
+	}, DefaultPriority, language.English)
+}
+
+func TestNewReturnsTextMap(t *testing.T) {
+	r := newFallbackRegistry()
+
+	tm, ok := r.New(language.BrazilianPortuguese).(TextMap)
+	if !ok {
+		t.Fatal("not a text map")
+	}
+
+	if s := tm.Text(Hello); s != "Oi Mundo" {
+		t.Error("text", s)
+	}
+}
+
+type wrappedRegistry struct {
+	TextRegistry
+}
+
+func TestNewFinderWrapsRegistry(t *testing.T) {
+	r := wrappedRegistry{newFallbackRegistry()}
+
+	tf := NewFinder(r, "pt-BR", pack)
+	if tf.Language() != language.BrazilianPortuguese || tf.Text(Hello) != "Hello World" {
+		t.Error("wrapped", tf.Language(), tf.Text(Hello))
+	}
+}
+
+func TestNewCachesFinders(t *testing.T) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+
+	tf := NewFinder(r, language.English)
+	if NewFinder(r, language.English) != tf || NewFinder(r, "en") != tf {
+		t.Error("not cached")
+	}
+
+	if calls != 1 {
+		t.Error("calls", calls)
+	}
+
+	// french is not registered so resolves to the english finder
+	if NewFinder(r, language.French) != tf || calls != 1 {
+		t.Error("french not resolved to english", calls)
+	}
+
+	r = newFallbackRegistry()
+	if NewFinder(r, language.BrazilianPortuguese) == NewFinder(r, language.English) {
+		t.Error("portuguese cached as english")
+	}
+}
+
+func TestNewCacheInvalidatedByRegister(t *testing.T) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+
+	tf := NewFinder(r, language.English)
+
+	r.Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		return additionalPack
+	}, Override, language.English)
+
+	tf2 := NewFinder(r, language.English)
+	if tf2 == tf || calls != 2 {
+		t.Error("not invalidated", calls)
+	}
+
+	if s := tf2.Text(Hello); s != "Hello Moon" {
+		t.Error("text", s)
+	}
+}
+
+func TestNewNotCachedWithTextMaps(t *testing.T) {
+	r := NewRegistry()
+
+	if NewFinder(r, pack) == NewFinder(r, pack) {
+		t.Error("cached text map")
+	}
+
+}
+
+func TestNewCachesResolvedLanguages(t *testing.T) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+
+	tf := NewFinder(r, "en", "fr", "de", "es", "it")
+	if NewFinder(r, "en", "fr", "de", "es", "it") != tf {
+		t.Error("many tags not cached")
+	}
+
+	// different tags resolving to the same registered languages share the finder
+	if NewFinder(r, "en-GB", "de", "it", "pt", "nl") != tf || NewFinder(r, "en-US") != tf {
+		t.Error("resolved language not cached")
+	}
+
+	if calls != 1 {
+		t.Error("calls", calls)
+	}
+}
+
+func TestNewCachedAllocationFree(t *testing.T) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+	options := []interface{}{language.French, language.English}
+
+	r.New(options...)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.New(options...)
+	})
+
+	if allocs != 0 {
+		t.Error("allocs", allocs)
+	}
+}
+
+func BenchmarkNewCached(b *testing.B) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+	options := []interface{}{language.French, language.English}
+
+	r.New(options...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.New(options...)
+	}
+}
+
+func BenchmarkNewUncached(b *testing.B) {
+	calls := 0
+	r := newCountingRegistry(&calls)
+	options := []interface{}{language.French, language.English, pack}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.New(options...)
+	}
+}
+
+func TestConcurrentProviderBuiltOnce(t *testing.T) {
+	var calls int32
+	r := NewRegistry().Register(ExamplePackID, func(packID PackID, langTag Tag) TextMap {
+		atomic.AddInt32(&calls, 1)
+		return pack
+	}, DefaultPriority, language.English)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s := r.Text(Hello); s != "Hello World" {
+				t.Error("text", s)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Error("calls", calls)
+	}
+}
+
+type stressPackID int
+
+func TestConcurrentRegisterAndFind(t *testing.T) {
+	r := NewRegistry()
+
+	const registrations = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := testTextID(100 + i)
+			r.Register(stressPackID(i), func(packID PackID, langTag Tag) TextMap {
+				return TextMap{id: id.String()}
+			}, DefaultPriority, language.English)
+		}(i)
+	}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				r.Find(testTextID(100 + j))
+				r.Text(testTextID(100 + i))
+				r.(LanguageFinder).Language()
+				r.New(language.English).Find(testTextID(100 + j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < registrations; i++ {
+		id := testTextID(100 + i)
+		if s, ok := r.Find(id); !ok || s != id.String() {
+			t.Error("find", id, s, ok)
+		}
+
+		if s := r.New(language.English).Text(id); s != id.String() {
+			t.Error("new", id, s)
+		}
+	}
+}
@@ -74,3 +74,54 @@ func (tm TextMap) Find(textID TextID) (t string, found bool) {
 	f, ok := tm[textID]
 	return f, ok
 }
+
+// languageTextMap is a TextMap bound to the language its text was resolved for.
+type languageTextMap struct {
+	TextMap
+	langTag Tag
+	sources map[TextID]Tag  // the registered language supplying each text, nil unless created by a registry
+	missing *missingHandler // nil unless created by a registry
+	policy  *MissingPolicy  // the finder's policy, nil to use the registry's
+}
+
+// NewLanguageFinder creates a LanguageFinder for the language langTag by merging zero or more exiting maps.
+func NewLanguageFinder(langTag Tag, texts ...TextMap) LanguageFinder {
+	return newLanguageTextMap(langTag, NewTextMap(texts...))
+}
+
+func newLanguageTextMap(langTag Tag, tm TextMap) *languageTextMap {
+	return &languageTextMap{TextMap: tm, langTag: langTag}
+}
+
+// Language returns the language tag the text map was resolved for.
+func (tm *languageTextMap) Language() Tag {
+	return tm.langTag
+}
+
+// Text returns the text identified by the textID, or if missing the text selected by the finder's MissingPolicy.
+func (tm *languageTextMap) Text(textID TextID) string {
+	if t, ok := tm.TextMap[textID]; ok {
+		return t
+	}
+
+	if tm.reportMissing(textID) == MissingMarker {
+		return missingMarker(textID)
+	}
+	return ""
+}
+
+// reportMissing reports that the text of id was missing when rendered, returning the policy's mode.
+func (tm *languageTextMap) reportMissing(id TextID) MissingMode {
+	if tm.missing == nil {
+		return MissingFallback
+	}
+	return tm.missing.report(tm.policy, tm.langTag, id)
+}
+
+// missingMode returns the mode of the finder's policy.
+func (tm *languageTextMap) missingMode() MissingMode {
+	if tm.missing == nil {
+		return MissingFallback
+	}
+	return tm.missing.current(tm.policy).Mode
+}
@@ -27,8 +27,8 @@ type (
 	// Source/package family should use a unique type to identify its messages
 	// valid types must be of an integer (intX or UIntX) type, a string or a struct
 	// The provider will panic if any other type is used
-	// If the identifier supports exterr.ErrorCoder or fmt.Stringer these interfaces will be used
-	// when rasing errors using Errorf or Sprintf.
+	// If the identifier supports ErrorCoder or fmt.Stringer these interfaces will be used
+	// when rasing errors using Errorf or Sprintf, see TextCode.
 	TextID interface {
 		fmt.Stringer
 		// Single is the id for the singular version of the text.
@@ -37,6 +37,13 @@ type (
 		Plural() TextID
 	}
 
+	// ErrorCoder is implemented by text ids that provide their own machine-readable error code.
+	// If a TextID does not implement ErrorCoder the String version of its Single id is used as the code.
+	ErrorCoder interface {
+		// Code returns the error code of the id.
+		Code() string
+	}
+
 	// PackID uses Go's strong typing system to create a unique identifier for a collection of string resources
 	// The pack ID is used to link different language version of the same pack together.
 	PackID = interface{}
@@ -52,12 +59,43 @@ type (
 		// Find looks up the passed textID key and returns true if found
 		Find(textID TextID) (t string, found bool)
 	}
+
+	// LanguageFinder is a TextFinder that knows the language of the text it finds.
+	// The language is used to select CLDR plural variants of a message.
+	// Finders created by NewFinder implement LanguageFinder.
+	LanguageFinder interface {
+		TextFinder
+
+		// Language returns the language tag the finder's text was resolved for.
+		Language() Tag
+	}
+
+	// SourceFinder is a TextFinder that knows which language supplied each text it finds, which
+	// differs from the finder's language when text falls back along a FallbackChain.
+	// Finders created by NewFinder implement SourceFinder.
+	SourceFinder interface {
+		TextFinder
+
+		// Source returns the language that supplied the text of textID, false if the text is missing.
+		Source(textID TextID) (Tag, bool)
+	}
 )
 
 // ByCount returns the plural version of a count if count 1= 1.
+// ByCount uses the english plural rules, FindCount supports the CLDR rules of other languages.
 func ByCount(id TextID, count int) TextID {
 	if count != 1 {
 		return id.Plural()
 	}
 	return id.Single()
 }
+
+// TextCode returns the language independent code of a text id.
+// The ErrorCoder interface is used if supported, otherwise the String version of the id's
+// Single version is used, see ReflectCoderString for a typical implementation.
+func TextCode(id TextID) string {
+	if coder, ok := id.(ErrorCoder); ok {
+		return coder.Code()
+	}
+	return id.Single().String()
+}
@@ -0,0 +1,318 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// FormatProblemKind classifies the differences found between the fmt verbs of a translation and
+// the default language text.
+type FormatProblemKind int
+
+const (
+	// FormatCount the translation uses more args than the default language text, or fewer without
+	// using explicit %[n] indexes, so fmt reports missing or extra args.
+	FormatCount = FormatProblemKind(iota)
+
+	// FormatOrder the translation formats the args in a different order without using explicit %[n] indexes.
+	FormatOrder
+
+	// FormatVerb the translation formats an arg with a verb of a different kind, e.g. %s for a %d.
+	FormatVerb
+)
+
+// String returns the name of the kind.
+func (k FormatProblemKind) String() string {
+	switch k {
+	case FormatCount:
+		return "count"
+	case FormatOrder:
+		return "order"
+	case FormatVerb:
+		return "verb"
+	}
+	return fmt.Sprintf("FormatProblemKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name.
+func (k FormatProblemKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// FormatProblem is a difference between the fmt verbs of a translation and the default language text.
+type FormatProblem struct {
+	// PackID and Pack are the id and name of the pack, set by ValidateRegistry.
+	PackID PackID `json:"-"`
+	Pack   string `json:"pack,omitempty"`
+
+	// Language is the language of the translation, set by ValidateRegistry.
+	Language Tag `json:"language"`
+
+	// ID and Key are the TextMap key of the text and its KeyName.
+	ID  TextID `json:"-"`
+	Key string `json:"key"`
+
+	// Kind classifies the problem.
+	Kind FormatProblemKind `json:"kind"`
+
+	// Arg is the one based index of the arg with a FormatVerb problem.
+	Arg int `json:"arg,omitempty"`
+
+	// Default and Text are the default language text and the translation.
+	Default string `json:"default"`
+	Text    string `json:"text"`
+}
+
+// String describes the problem.
+func (p FormatProblem) String() string {
+	var s string
+	if p.Pack != "" {
+		s = p.Pack + " "
+	}
+
+	s += p.Language.String() + " " + p.Key + ": "
+
+	switch p.Kind {
+	case FormatCount:
+		s += "arg count differs"
+	case FormatOrder:
+		s += "args reordered without explicit indexes"
+	case FormatVerb:
+		s += fmt.Sprintf("verb for arg %d differs", p.Arg)
+	default:
+		s += p.Kind.String()
+	}
+
+	return s + fmt.Sprintf(" in %q, default %q", p.Text, p.Default)
+}
+
+// verbKind groups the fmt verbs that accept the same types of arg.
+type verbKind int
+
+const (
+	anyVerb = verbKind(iota)
+	boolVerb
+	integerVerb
+	floatVerb
+	stringVerb
+	hexVerb
+	pointerVerb
+	unknownVerb
+)
+
+// kindOf returns the kind of a verb, a * width or precision taking an integer.
+func kindOf(verb rune) verbKind {
+	switch verb {
+	case 'v', 'T':
+		return anyVerb
+	case 't':
+		return boolVerb
+	case 'b', 'c', 'd', 'o', 'O', 'U', '*':
+		return integerVerb
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return floatVerb
+	case 's', 'q':
+		return stringVerb
+	case 'x', 'X':
+		return hexVerb
+	case 'p':
+		return pointerVerb
+	}
+	return unknownVerb
+}
+
+// compatible reports if a translation may format an arg with a verb of kind tr in place of a verb of kind ref.
+// Any arg may be formatted with %v and hex verbs accept integers, floats and strings.
+func compatible(ref, tr verbKind) bool {
+	switch {
+	case tr == ref, tr == anyVerb:
+		return true
+	case tr == hexVerb:
+		return ref == integerVerb || ref == floatVerb || ref == stringVerb
+	case ref == hexVerb:
+		return tr == integerVerb || tr == floatVerb || tr == stringVerb
+	}
+	return false
+}
+
+// argKinds returns the kind of the first verb formatting each arg.
+func argKinds(verbs []formatVerb) map[int]verbKind {
+	kinds := make(map[int]verbKind, len(verbs))
+	for _, v := range verbs {
+		if _, ok := kinds[v.Arg]; !ok {
+			kinds[v.Arg] = kindOf(v.Verb)
+		}
+	}
+	return kinds
+}
+
+// argCount returns the number of args the verbs use.
+func argCount(verbs []formatVerb) int {
+	n := 0
+	for _, v := range verbs {
+		if v.Arg >= n {
+			n = v.Arg + 1
+		}
+	}
+	return n
+}
+
+// ValidateFormat compares the fmt verbs of a translation with the default language text, returning
+// the problems found, without the Key, Pack and Language set.
+//
+// A translation may use fewer args than the default only if it uses explicit %[n] indexes, it
+// may format an arg with %v in place of any verb and may swap between hex and integer, float or
+// string verbs.
+func ValidateFormat(defaultText, text string) []FormatProblem {
+	refVerbs, _ := parseVerbs(defaultText)
+	trVerbs, reordered := parseVerbs(text)
+
+	problem := func(kind FormatProblemKind, arg int) []FormatProblem {
+		return []FormatProblem{{Kind: kind, Arg: arg, Default: defaultText, Text: text}}
+	}
+
+	refCount, trCount := argCount(refVerbs), argCount(trVerbs)
+	if trCount > refCount || (trCount < refCount && !reordered) {
+		return problem(FormatCount, 0)
+	}
+
+	refKinds, trKinds := argKinds(refVerbs), argKinds(trVerbs)
+
+	var problems []FormatProblem
+	reported := make(map[int]bool)
+
+	for _, v := range trVerbs {
+		ref, ok := refKinds[v.Arg]
+		if ok && !reported[v.Arg] && !compatible(ref, kindOf(v.Verb)) {
+			reported[v.Arg] = true
+			problems = append(problems, problem(FormatVerb, v.Arg+1)...)
+		}
+	}
+
+	// verbs of the wrong kind in the order of the default text suggest the args were reordered
+	if len(problems) > 0 && !reordered && sameKinds(refKinds, trKinds) {
+		return problem(FormatOrder, 0)
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Arg < problems[j].Arg
+	})
+
+	return problems
+}
+
+// sameKinds reports if the same kinds of verb are used, ignoring the args they format.
+func sameKinds(a, b map[int]verbKind) bool {
+	counts := make(map[verbKind]int)
+	for _, k := range a {
+		counts[k]++
+	}
+	for _, k := range b {
+		counts[k]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateFormats compares the fmt verbs of the texts of a translation with the default language texts
+// with the same key, returning the problems found ordered by key.  Plural category variants are compared
+// with the default language's variant if it has one, otherwise its plural or single text.
+func ValidateFormats(defaults, translation TextMap) []FormatProblem {
+	var problems []FormatProblem
+
+	for k, text := range translation {
+		ref, ok := referenceText(defaults, k)
+		if !ok {
+			continue
+		}
+
+		for _, p := range ValidateFormat(ref, text) {
+			p.ID, p.Key = k, KeyName(k)
+			problems = append(problems, p)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Key < problems[j].Key
+	})
+
+	return problems
+}
+
+// referenceText returns the default language text a translation with the key is compared against.
+func referenceText(defaults TextMap, k TextID) (string, bool) {
+	if t, ok := defaults[k]; ok {
+		return t, true
+	}
+
+	if k == k.Single() || k == k.Plural() {
+		return "", false
+	}
+
+	if t, ok := defaults[k.Plural()]; ok {
+		return t, true
+	}
+
+	t, ok := defaults[k.Single()]
+	return t, ok
+}
+
+// ValidateRegistry compares the fmt verbs of the text registered in each language of each pack with the
+// text registered in the DefaultLanguage, returning the problems found ordered by pack, language and key.
+// It is intended to be called at startup or in tests.  Registries that do not implement IntrospectRegistry
+// have no problems.
+func ValidateRegistry(r TextRegistry) []FormatProblem {
+	ir, ok := r.(IntrospectRegistry)
+	if !ok {
+		return nil
+	}
+
+	defaultTag := language.MustParse(DefaultLanguage)
+
+	var problems []FormatProblem
+
+	for _, info := range ir.Packs() {
+		refTag := matchLanguage(info.Languages, []Tag{defaultTag})
+		defaults := ir.PackText(info.ID, refTag)
+
+		tags := append([]Tag(nil), info.Languages...)
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].String() < tags[j].String()
+		})
+
+		for _, tag := range tags {
+			if tag == refTag {
+				continue
+			}
+
+			for _, p := range ValidateFormats(defaults, ir.PackText(info.ID, tag)) {
+				p.PackID, p.Pack, p.Language = info.ID, info.Name, tag
+				problems = append(problems, p)
+			}
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestValidateFormat(t *testing.T) {
+	for _, tc := range []struct {
+		defaultText, text string
+		kinds             []FormatProblemKind
+		arg               int
+	}{
+		{"%d items", "%d éléments", nil, 0},
+		{"%d items", "%v éléments", nil, 0},
+		{"%d items", "%x items", nil, 0},
+		{"no args", "100%% sans", nil, 0},
+		{"%s has %d", "%[2]d chez %[1]s", nil, 0},
+		{"%s has %d", "%[2]d items", nil, 0},
+		{"%d items", "%s éléments", []FormatProblemKind{FormatVerb}, 1},
+		{"%v items", "%d éléments", []FormatProblemKind{FormatVerb}, 1},
+		{"%s has %d", "%[2]s chez %[1]d", []FormatProblemKind{FormatVerb, FormatVerb}, 2},
+		{"%s has %d", "%d chez %s", []FormatProblemKind{FormatOrder}, 0},
+		{"%d items", "éléments", []FormatProblemKind{FormatCount}, 0},
+		{"%d items", "%d %s", []FormatProblemKind{FormatCount}, 0},
+		{"%s has %d", "%[3]d chez %[1]s", []FormatProblemKind{FormatCount}, 0},
+	} {
+		problems := ValidateFormat(tc.defaultText, tc.text)
+
+		if len(problems) != len(tc.kinds) {
+			t.Error(tc.text, problems)
+			continue
+		}
+
+		for i, p := range problems {
+			if p.Kind != tc.kinds[i] || p.Default != tc.defaultText || p.Text != tc.text {
+				t.Error(tc.text, p)
+			}
+		}
+
+		// problems are ordered by arg
+		if len(problems) > 0 && problems[len(problems)-1].Arg != tc.arg {
+			t.Error(tc.text, "arg", problems)
+		}
+	}
+}
+
+func TestValidateFormats(t *testing.T) {
+	defaults := TextMap{Hello: "Hello World", Args: "%d file", -Args: "%d files"}
+	translation := TextMap{
+		Hello:                        "Bonjour %s",
+		Args:                         "%d fichier",
+		-Args:                        "%s fichiers",
+		ByCategory(Args, PluralFew):  "%d pliki",
+		ByCategory(Args, PluralMany): "plików",
+		None:                         "%s",
+	}
+
+	problems := ValidateFormats(defaults, translation)
+	if len(problems) != 3 {
+		t.Fatal(problems)
+	}
+
+	if p := problems[0]; p.ID != Hello || p.Key != "2" || p.Kind != FormatCount {
+		t.Error("hello", p)
+	}
+
+	if p := problems[1]; p.ID != -Args || p.Key != "3:plural" || p.Kind != FormatVerb || p.Arg != 1 {
+		t.Error("plural", p)
+	}
+
+	if p := problems[2]; p.Key != "3[many]" || p.Kind != FormatCount || p.Default != "%d files" {
+		t.Error("variant", p)
+	}
+}
+
+func TestValidateRegistry(t *testing.T) {
+	problems := ValidateRegistry(newCoverageRegistry())
+
+	if len(problems) != 1 {
+		t.Fatal(problems)
+	}
+
+	p := problems[0]
+	if p.PackID != ExamplePackID || p.Language != language.Spanish || p.Key != "3" || p.Kind != FormatVerb {
+		t.Error(p)
+	}
+
+	if s := p.String(); s != `lpax.TestPackID(1) es 3: verb for arg 1 differs in "Uno %d", default "Single %v"` {
+		t.Error(s)
+	}
+
+	b, err := json.Marshal(problems)
+	if err != nil || !strings.Contains(string(b), `"language":"es","key":"3","kind":"verb","arg":1`) {
+		t.Error(string(b), err)
+	}
+
+	if problems := ValidateRegistry(wrappedRegistry{newCoverageRegistry()}); problems != nil {
+		t.Error("not introspectable", problems)
+	}
+}
+
+func TestFormatProblemKindString(t *testing.T) {
+	if FormatOrder.String() != "order" || FormatProblemKind(9).String() != "FormatProblemKind(9)" {
+		t.Error(FormatOrder, FormatProblemKind(9))
+	}
+}
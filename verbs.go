@@ -0,0 +1,50 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import "github.com/nehemming/lpax/internal/printf"
+
+// formatVerb is an arg formatted by a fmt format string.
+type formatVerb struct {
+	// Arg is the zero based index of the arg formatted.
+	Arg int
+
+	// Verb is the verb, or '*' for a width or precision taken from an arg.
+	Verb rune
+}
+
+// parseVerbs returns the args formatted by a fmt format string in order, following fmt's rules for
+// explicit [n] argument indexes, and if any explicit indexes are used.  %% is not a verb.
+func parseVerbs(format string) (verbs []formatVerb, reordered bool) {
+	for _, v := range printf.Parse(format) {
+		reordered = reordered || v.Indexed
+
+		for _, arg := range v.StarArgs {
+			verbs = append(verbs, formatVerb{Arg: arg, Verb: '*'})
+		}
+
+		if v.Verb == 0 {
+			break
+		}
+
+		if v.Verb != '%' {
+			verbs = append(verbs, formatVerb{Arg: v.Arg, Verb: v.Verb})
+		}
+	}
+
+	return verbs, reordered
+}
@@ -0,0 +1,347 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// WatchEvent reports the reload of a pack by a Watcher.
+	WatchEvent struct {
+		// Pack is the pack that changed.
+		Pack FSPack
+
+		// Languages are the languages of the pack after the reload.
+		Languages []Tag
+
+		// Err is the error reading the pack, if not nil the previously loaded text remains in use.
+		Err error
+	}
+
+	// Watcher keeps the packs stored as <root>/<bcp47 language>/<pack name>.json in an fs.FS, such as
+	// an os.DirFS, registered with a registry, polling the files for changes and reloading them.
+	Watcher struct {
+		registry TextRegistry
+		fsys     fs.FS
+		root     string
+		priority Priority
+		onEvent  func(WatchEvent)
+		mu       sync.Mutex // serializes reloads
+		packs    []*watchedPack
+	}
+
+	// watchedPack is the currently loaded version of a pack.
+	watchedPack struct {
+		FSPack
+		packs      atomic.Value // JSONPacks
+		files      map[string]fileState
+		registered map[Tag]bool
+	}
+
+	// fileState is used to detect changes to a file.  The content hash detects changes that keep the
+	// size and fall within the resolution of the file system's modification times.
+	fileState struct {
+		modTime time.Time
+		size    int64
+		sum     [sha256.Size]byte
+	}
+)
+
+// NewWatcher reads the packs stored as <root>/<bcp47 language>/<pack name>.json in fsys and registers them
+// with the registry for the languages found with the passed priority.  An error is returned if no files are
+// found for a pack.  onEvent, which may be nil, is called each time a pack is reloaded.
+//
+// Changes are loaded by calling Reload or Run.  When a pack's files change their text replaces the previous
+// version atomically and the registry, if it implements RefreshRegistry, is refreshed so new finders see the
+// new text.
+func NewWatcher(r TextRegistry, fsys fs.FS, root string, priority Priority, onEvent func(WatchEvent), packs ...FSPack) (*Watcher, error) {
+	w := &Watcher{
+		registry: r,
+		fsys:     fsys,
+		root:     root,
+		priority: priority,
+		onEvent:  onEvent,
+	}
+
+	for _, p := range packs {
+		wp := &watchedPack{FSPack: p, registered: make(map[Tag]bool)}
+
+		files, err := w.stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := ReadFSPacks(fsys, root, p.Name, p.Decode)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no files found for pack %q", p.Name)
+		}
+
+		wp.files = files
+		wp.packs.Store(found)
+		w.register(wp, found.Tags())
+
+		w.packs = append(w.packs, wp)
+	}
+
+	return w, nil
+}
+
+// onRegister returns the texts of the currently loaded version of the pack.
+func (wp *watchedPack) onRegister(packID PackID, langTag Tag) TextMap {
+	return wp.packs.Load().(JSONPacks).OnRegister(packID, langTag)
+}
+
+// register registers the pack for the languages found.  Languages whose files have been removed are
+// unregistered, so their text falls back along the registry's FallbackChain, if the registry supports it,
+// otherwise the pack is registered for the languages it has not already been registered for.
+func (w *Watcher) register(wp *watchedPack, langTags []Tag) {
+	if r, ok := w.registry.(languageRegistrar); ok {
+		r.registerLanguages(wp, wp.ID, wp.onRegister, w.priority, langTags)
+		return
+	}
+
+	added := make([]Tag, 0, len(langTags))
+	for _, tag := range langTags {
+		if !wp.registered[tag] {
+			wp.registered[tag] = true
+			added = append(added, tag)
+		}
+	}
+
+	w.registry.Register(wp.ID, wp.onRegister, w.priority, added...)
+}
+
+// stat returns the state of the pack's files.
+func (w *Watcher) stat(p FSPack) (map[string]fileState, error) {
+	entries, err := fs.ReadDir(w.fsys, w.root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileState)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		name := path.Join(w.root, entry.Name(), p.Name+".json")
+
+		info, err := fs.Stat(w.fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(w.fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		files[name] = fileState{modTime: info.ModTime(), size: info.Size(), sum: sha256.Sum256(data)}
+	}
+
+	return files, nil
+}
+
+// changed reports if the pack's files differ from the loaded version.
+func (wp *watchedPack) changed(files map[string]fileState) bool {
+	if len(files) != len(wp.files) {
+		return true
+	}
+
+	for name, state := range files {
+		if loaded, ok := wp.files[name]; !ok || !loaded.modTime.Equal(state.modTime) || loaded.size != state.size || loaded.sum != state.sum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reload reloads the packs whose files have been added, removed or modified since they were last loaded,
+// returning the first error encountered.  Packs that fail to load keep their previous text and are retried
+// on the next call.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var firstErr error
+	reloaded := false
+	events := make([]WatchEvent, 0, len(w.packs))
+
+	for _, wp := range w.packs {
+		event, ok := w.reload(wp)
+		if !ok {
+			continue
+		}
+
+		if event.Err != nil && firstErr == nil {
+			firstErr = event.Err
+		}
+		reloaded = reloaded || event.Err == nil
+
+		events = append(events, event)
+	}
+
+	// refresh before reporting the events so observers see the new text
+	if r, ok := w.registry.(RefreshRegistry); ok && reloaded {
+		r.Refresh()
+	}
+
+	if w.onEvent != nil {
+		for _, event := range events {
+			w.onEvent(event)
+		}
+	}
+
+	return firstErr
+}
+
+// reload reloads a pack if its files have changed, returning false if they have not.
+func (w *Watcher) reload(wp *watchedPack) (WatchEvent, bool) {
+	event := WatchEvent{Pack: wp.FSPack}
+
+	files, err := w.stat(wp.FSPack)
+	if err == nil && !wp.changed(files) {
+		return event, false
+	}
+
+	var found JSONPacks
+	if err == nil {
+		found, err = ReadFSPacks(w.fsys, w.root, wp.Name, wp.Decode)
+	}
+
+	if err != nil {
+		event.Err = err
+		return event, true
+	}
+
+	event.Languages = found.Tags()
+
+	wp.files = files
+	wp.packs.Store(found)
+	w.register(wp, event.Languages)
+
+	return event, true
+}
+
+// Run calls Reload every interval until the context is cancelled.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// errors are reported to onEvent
+			_ = w.Reload()
+		}
+	}
+}
+
+type (
+	// liveFinder looks up text using the registry's current finder.
+	liveFinder struct {
+		registry TextRegistry
+		options  []interface{}
+		snapshot atomic.Value // *liveSnapshot
+	}
+
+	// liveSnapshot is the finder created for a live finder's options and the registry sequence it was created at.
+	liveSnapshot struct {
+		sequence uint64
+		finder   LanguageFinder
+	}
+
+	// sequencedRegistry is implemented by registries whose sequence changes each time finders they created
+	// may be out of date.
+	sequencedRegistry interface {
+		sequence() uint64
+	}
+)
+
+// NewLiveFinder returns a finder that looks up text using the finder NewFinder returns from the registry
+// for the options at the time of each lookup.  Unlike the finders returned by NewFinder, which are snapshots of
+// the text registered when they were created, a live finder bound to a long lived context sees text reloaded
+// by a Watcher.  The live finder keeps the finder created for its options until the registry changes, so
+// lookups remain cheap whatever the options.
+func NewLiveFinder(r TextRegistry, options ...interface{}) LanguageFinder {
+	return &liveFinder{registry: r, options: append([]interface{}(nil), options...)}
+}
+
+func (lf *liveFinder) current() LanguageFinder {
+	r, ok := lf.registry.(sequencedRegistry)
+	if !ok {
+		return NewFinder(lf.registry, lf.options...)
+	}
+
+	// the sequence is read first so a change while the finder is created is seen by the next lookup
+	sequence := r.sequence()
+	if s, ok := lf.snapshot.Load().(*liveSnapshot); ok && s.sequence == sequence {
+		return s.finder
+	}
+
+	finder := NewFinder(lf.registry, lf.options...)
+	lf.snapshot.Store(&liveSnapshot{sequence: sequence, finder: finder})
+
+	return finder
+}
+
+// Text returns the text identified by the textID or an empty string.
+func (lf *liveFinder) Text(textID TextID) string {
+	return lf.current().Text(textID)
+}
+
+// Find looks up the passed textID key and returns true if found.
+func (lf *liveFinder) Find(textID TextID) (string, bool) {
+	return lf.current().Find(textID)
+}
+
+// Language returns the language of the current finder.
+func (lf *liveFinder) Language() Tag {
+	return lf.current().Language()
+}
+
+// reportMissing reports that the text of id was missing from the current finder when rendered.
+func (lf *liveFinder) reportMissing(id TextID) MissingMode {
+	return reportMissing(lf.current(), id)
+}
+
+// missingMode returns the mode of the current finder's policy.
+func (lf *liveFinder) missingMode() MissingMode {
+	if r, ok := lf.current().(missingReporter); ok {
+		return r.missingMode()
+	}
+	return MissingFallback
+}
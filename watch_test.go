@@ -0,0 +1,292 @@
+/*
+Copyright (c) 2021 The lpax Authors (Neil Hemming)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lpax
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// writePackFile writes a pack file, advancing its modification time so the change is always detected.
+func writePackFile(t *testing.T, dir, lang, data string, offset int) {
+	t.Helper()
+
+	name := filepath.Join(dir, lang, "example.json")
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Now().Add(time.Duration(offset) * time.Second)
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []WatchEvent
+}
+
+func (er *eventRecorder) onEvent(e WatchEvent) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.events = append(er.events, e)
+}
+
+func (er *eventRecorder) count() int {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	return len(er.events)
+}
+
+func newTestWatcher(t *testing.T) (string, TextRegistry, *Watcher, *eventRecorder) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writePackFile(t, dir, "en", `{"texts": {"hello": "Hello World"}}`, 0)
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Bonjour"}}`, 0)
+
+	r := NewRegistry()
+	recorder := &eventRecorder{}
+
+	w, err := NewWatcher(r, os.DirFS(dir), ".", DefaultPriority, recorder.onEvent,
+		FSPack{ID: ExamplePackID, Name: "example", Decode: decodeTestTextID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, r, w, recorder
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir, r, w, recorder := newTestWatcher(t)
+
+	ctx := WithContext(context.Background(), NewLiveFinder(r, language.French))
+	snapshot := r.New(language.French)
+
+	if s := CtxSprintf(ctx, Hello); s != "Bonjour" {
+		t.Fatal("initial", s)
+	}
+
+	// nothing changed
+	if err := w.Reload(); err != nil || recorder.count() != 0 {
+		t.Fatal("unchanged", err, recorder.count())
+	}
+
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Bonjour le monde"}}`, 10)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder.count() != 1 || recorder.events[0].Pack.Name != "example" || len(recorder.events[0].Languages) != 2 {
+		t.Fatal("events", recorder.events)
+	}
+
+	if s := r.New(language.French).Text(Hello); s != "Bonjour le monde" {
+		t.Error("new finder", s)
+	}
+
+	if s := CtxSprintf(ctx, Hello); s != "Bonjour le monde" {
+		t.Error("live finder", s)
+	}
+
+	if s := snapshot.Text(Hello); s != "Bonjour" {
+		t.Error("snapshot", s)
+	}
+
+	if lf, ok := FromContext(ctx).(LanguageFinder); !ok || lf.Language() != language.French {
+		t.Error("language")
+	}
+}
+
+func TestWatcherNewLanguage(t *testing.T) {
+	dir, r, w, _ := newTestWatcher(t)
+
+	if s := r.New(language.German).Text(Hello); s != "Hello World" {
+		t.Fatal("fallback", s)
+	}
+
+	writePackFile(t, dir, "de", `{"texts": {"hello": "Hallo Welt"}}`, 10)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := r.New(language.German).Text(Hello); s != "Hallo Welt" {
+		t.Error("german", s)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "de", "example.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	// german is unregistered so its text falls back to english
+	tf := NewFinder(r, language.German)
+	if s := tf.Text(Hello); s != "Hello World" {
+		t.Error("removed", s)
+	}
+
+	if tf.Language() != language.English {
+		t.Error("language", tf.Language())
+	}
+
+	for _, info := range r.(IntrospectRegistry).Packs() {
+		for _, tag := range info.Languages {
+			if tag == language.German {
+				t.Error("german registered", info.Languages)
+			}
+		}
+	}
+}
+
+func TestWatcherSameSizeAndTime(t *testing.T) {
+	dir, r, w, recorder := newTestWatcher(t)
+
+	// the same size and modification time, only the content hash differs
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Bonjoux"}}`, 0)
+	name := filepath.Join(dir, "fr", "example.json")
+	loaded := w.packs[0].files["fr/example.json"].modTime
+	if err := os.Chtimes(name, loaded, loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reload(); err != nil || recorder.count() != 1 {
+		t.Fatal("reload", err, recorder.count())
+	}
+
+	if s := r.New(language.French).Text(Hello); s != "Bonjoux" {
+		t.Error("content", s)
+	}
+}
+
+func TestLiveFinderCache(t *testing.T) {
+	dir, r, w, _ := newTestWatcher(t)
+
+	lf := NewLiveFinder(r, language.French, MissingPolicy{Mode: MissingMarker}).(*liveFinder)
+
+	first := lf.current()
+	if lf.current() != first {
+		t.Error("not cached")
+	}
+
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Bonjour le monde"}}`, 10)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lf.current() == first || lf.Text(Hello) != "Bonjour le monde" {
+		t.Error("not reloaded", lf.Text(Hello))
+	}
+
+	// registries without a sequence create a finder for each lookup
+	wrapped := NewLiveFinder(wrappedRegistry{r}, language.French).(*liveFinder)
+	if s := wrapped.Text(Hello); s != "Bonjour le monde" {
+		t.Error("wrapped", s)
+	}
+}
+
+func TestWatcherReloadError(t *testing.T) {
+	dir, r, w, recorder := newTestWatcher(t)
+
+	writePackFile(t, dir, "fr", `{`, 10)
+	if err := w.Reload(); err == nil {
+		t.Fatal("no error")
+	}
+
+	if recorder.count() != 1 || recorder.events[0].Err == nil {
+		t.Fatal("events", recorder.events)
+	}
+
+	if s := r.New(language.French).Text(Hello); s != "Bonjour" {
+		t.Error("previous text", s)
+	}
+
+	// retried until fixed
+	if err := w.Reload(); err == nil {
+		t.Error("no retry")
+	}
+
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Salut"}}`, 20)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := r.New(language.French).Text(Hello); s != "Salut" {
+		t.Error("fixed", s)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir, r, w, recorder := newTestWatcher(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		w.Run(ctx, time.Millisecond)
+	}()
+
+	writePackFile(t, dir, "fr", `{"texts": {"hello": "Coucou"}}`, 10)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if recorder.count() == 0 {
+		t.Error("not reloaded", recorder.count())
+	}
+
+	if s := r.New(language.French).Text(Hello); s != "Coucou" {
+		t.Error("french", s)
+	}
+}
+
+func TestNewWatcherErrors(t *testing.T) {
+	dir := t.TempDir()
+	pack := FSPack{ID: ExamplePackID, Name: "example", Decode: decodeTestTextID}
+
+	if _, err := NewWatcher(NewRegistry(), os.DirFS(dir), "missing", DefaultPriority, nil, pack); err == nil {
+		t.Error("missing root")
+	}
+
+	if _, err := NewWatcher(NewRegistry(), os.DirFS(dir), ".", DefaultPriority, nil, pack); err == nil {
+		t.Error("no files")
+	}
+
+	writePackFile(t, dir, "en", `{`, 0)
+	if _, err := NewWatcher(NewRegistry(), os.DirFS(dir), ".", DefaultPriority, nil, pack); err == nil {
+		t.Error("bad file")
+	}
+}